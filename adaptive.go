@@ -0,0 +1,156 @@
+package ratecounter
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// An AdaptiveRateCounter is a thread-safe counter like a RateCounter whose
+// resolution follows the rate: busy counters get more partials, for smoother
+// graphs, and idle ones fewer, so reads sum less.
+//
+// A RateCounter's partials can't be resized while it is live, so a change of
+// resolution starts a new RateCounter for new events and keeps the old one,
+// adding the two, until the old one's events have left the window. Nothing
+// is copied or dropped, and the resolution changes at most once an interval.
+type AdaptiveRateCounter struct {
+	// The *RateCounter new events are added to
+	current atomic.Value
+	// The counter replaced by the last change of resolution, until its
+	// events have left the window
+	retiring *RateCounter
+	// When the resolution last changed, in milliseconds
+	changed  uint64
+	interval time.Duration
+	min      int
+	max      int
+	opts     []Option
+	// Where the time comes from, in milliseconds, UnixMilli if nil
+	clock func() uint64
+	sync.Mutex
+}
+
+// NewAdaptiveRateCounter Constructs a new AdaptiveRateCounter for the
+// interval provided, starting with min partials and allowed up to max. The
+// options apply to each RateCounter behind it, except WithResolution.
+func NewAdaptiveRateCounter(intrvl time.Duration, min, max int, opts ...Option) *AdaptiveRateCounter {
+	if min < 1 {
+		panic("AdaptiveRateCounter resolution cannot be less than 1")
+	}
+	if max < min {
+		panic("AdaptiveRateCounter max resolution cannot be less than min")
+	}
+
+	// Read the options from a counter which is never used
+	probe := &RateCounter{partials: make([]partial, 20)}
+	for _, opt := range opts {
+		opt(probe)
+	}
+
+	a := &AdaptiveRateCounter{
+		interval: intrvl,
+		min:      min,
+		max:      max,
+		opts:     opts,
+		clock:    probe.clock,
+	}
+	a.current.Store(a.newCounter(min))
+	a.changed = a.now()
+	return a
+}
+
+// newCounter returns a RateCounter with the options and the resolution given
+func (a *AdaptiveRateCounter) newCounter(resolution int) *RateCounter {
+	opts := append(append([]Option(nil), a.opts...), WithResolution(resolution))
+	return NewRateCounter(a.interval, opts...)
+}
+
+// counter returns the RateCounter new events are added to
+func (a *AdaptiveRateCounter) counter() *RateCounter {
+	return a.current.Load().(*RateCounter)
+}
+
+// now returns the current time in milliseconds since the epoch
+func (a *AdaptiveRateCounter) now() uint64 {
+	if a.clock != nil {
+		return a.clock()
+	}
+	return UnixMilli()
+}
+
+// target returns the resolution for rate: the smallest doubling of min with
+// at least one event per partial, up to max
+func (a *AdaptiveRateCounter) target(rate int64) int {
+	res := a.min
+	for res < a.max && int64(res) < rate {
+		res *= 2
+	}
+	if res > a.max {
+		res = a.max
+	}
+	return res
+}
+
+// Incr Add an event into the AdaptiveRateCounter
+func (a *AdaptiveRateCounter) Incr(val int64) {
+	a.counter().Incr(val)
+}
+
+// Rate Return the number of events in the last interval, changing the
+// resolution if the rate calls for it
+func (a *AdaptiveRateCounter) Rate() int64 {
+	a.Lock()
+	defer a.Unlock()
+
+	now := a.now()
+	settled := now-a.changed >= uint64(a.interval.Nanoseconds()/1000000)
+	if settled && a.retiring != nil {
+		a.retiring.Close()
+		a.retiring = nil
+	}
+
+	cur := a.counter()
+	rate := cur.Rate()
+	if a.retiring != nil {
+		rate += a.retiring.Rate()
+		return rate
+	}
+	if !settled {
+		return rate
+	}
+
+	res := cur.resolution()
+	target := a.target(rate)
+	// Only drop partials once the rate is well below what they are for, so
+	// a rate near the boundary doesn't flip the resolution every interval
+	if target > res || (target < res && rate*4 <= int64(res)) {
+		a.retiring = cur
+		a.current.Store(a.newCounter(target))
+		a.changed = now
+	}
+	return rate
+}
+
+// Resolution returns the number of partials new events are counted in
+func (a *AdaptiveRateCounter) Resolution() int {
+	return a.counter().resolution()
+}
+
+// Close stops the counters behind the AdaptiveRateCounter, as
+// RateCounter.Close does
+func (a *AdaptiveRateCounter) Close() error {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.retiring != nil {
+		a.retiring.Close()
+		a.retiring = nil
+	}
+	return a.counter().Close()
+}
+
+func (a *AdaptiveRateCounter) String() string {
+	return strconv.FormatInt(a.Rate(), 10)
+}
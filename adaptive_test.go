@@ -0,0 +1,56 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveRateCounter(t *testing.T) {
+	clock := newManualClock()
+	r := NewAdaptiveRateCounter(time.Second, 2, 16, WithClock(clock))
+
+	if res := r.Resolution(); res != 2 {
+		t.Error("Expected ", res, " to equal ", 2)
+	}
+
+	// Busy: the resolution goes up, and no events are lost in the change
+	clock.Add(500 * time.Millisecond)
+	for i := 0; i < 100; i++ {
+		r.Incr(1)
+	}
+	clock.Add(500 * time.Millisecond)
+	if val := r.Rate(); val != 100 {
+		t.Error("Expected ", val, " to equal ", 100)
+	}
+	if res := r.Resolution(); res != 16 {
+		t.Error("Expected ", res, " to equal ", 16)
+	}
+	r.Incr(1)
+	if val := r.Rate(); val != 101 {
+		t.Error("Expected ", val, " to equal ", 101)
+	}
+
+	// The old events leave the window while the old counter retires
+	clock.Add(500 * time.Millisecond)
+	if val := r.Rate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+
+	// Idle: the resolution comes back down
+	clock.Add(time.Second)
+	if val := r.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+	if res := r.Resolution(); res != 2 {
+		t.Error("Expected ", res, " to equal ", 2)
+	}
+}
+
+func TestAdaptiveRateCounter_Bounds(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a max below min to panic")
+		}
+	}()
+	NewAdaptiveRateCounter(time.Second, 4, 2)
+}
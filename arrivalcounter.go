@@ -0,0 +1,123 @@
+package ratecounter
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// An ArrivalCounter is a thread-safe RateCounter which also tracks the gaps
+// between successive calls to 'Incr', for jitter analysis of schedulers and
+// heartbeats
+type ArrivalCounter struct {
+	rate *RateCounter
+	// Mean gap, in nanoseconds, over the interval
+	gaps     *AvgRateCounter
+	interval time.Duration
+	// Time of the last arrival
+	last time.Time
+	// The most recent gaps, for percentiles
+	recent []arrival
+	next   int
+	// Where the time comes from, in milliseconds, time.Now if nil
+	clock func() uint64
+	sync.Mutex
+}
+
+type arrival struct {
+	at  time.Time
+	gap time.Duration
+}
+
+// NewArrivalCounter constructs a new ArrivalCounter for the interval
+// provided, with opts applied to the counters behind it. Percentiles are
+// computed from at most the last 1024 gaps. With WithClock, gaps are
+// measured to the millisecond.
+func NewArrivalCounter(intrvl time.Duration, opts ...Option) *ArrivalCounter {
+	rate := NewRateCounter(intrvl, opts...)
+	return &ArrivalCounter{
+		rate: rate,
+		gaps: &AvgRateCounter{
+			hits:     NewRateCounter(intrvl, opts...),
+			counter:  NewRateCounter(intrvl, opts...),
+			interval: intrvl,
+		},
+		interval: intrvl,
+		recent:   make([]arrival, 0, 1024),
+		clock:    rate.clock,
+	}
+}
+
+// Incr Adds an event into the ArrivalCounter
+func (a *ArrivalCounter) Incr(val int64) {
+	a.rate.Incr(val)
+
+	now := clockTime(a.clock)
+	a.Lock()
+	last := a.last
+	a.last = now
+	if last.IsZero() {
+		a.Unlock()
+		return
+	}
+
+	gap := now.Sub(last)
+	if len(a.recent) < cap(a.recent) {
+		a.recent = append(a.recent, arrival{at: now, gap: gap})
+	} else {
+		a.recent[a.next] = arrival{at: now, gap: gap}
+		a.next = (a.next + 1) % len(a.recent)
+	}
+	a.Unlock()
+
+	a.gaps.Incr(gap.Nanoseconds())
+}
+
+// Rate Returns the number of events in the last interval
+func (a *ArrivalCounter) Rate() int64 {
+	return a.rate.Rate()
+}
+
+// MeanGap Returns the mean time between events during the last interval
+func (a *ArrivalCounter) MeanGap() time.Duration {
+	return time.Duration(a.gaps.Rate())
+}
+
+// GapPercentile Returns the q-th quantile, between 0 and 1, of the time
+// between events during the last interval
+func (a *ArrivalCounter) GapPercentile(q float64) time.Duration {
+	if q < 0 || q > 1 {
+		panic("ArrivalCounter quantile must be between 0 and 1")
+	}
+
+	cutoff := clockTime(a.clock).Add(-a.interval)
+
+	a.Lock()
+	gaps := make([]time.Duration, 0, len(a.recent))
+	for _, r := range a.recent {
+		if r.at.After(cutoff) {
+			gaps = append(gaps, r.gap)
+		}
+	}
+	a.Unlock()
+
+	if len(gaps) == 0 {
+		return 0
+	}
+
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+	idx := int(math.Ceil(q*float64(len(gaps)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+
+	return gaps[idx]
+}
+
+func (a *ArrivalCounter) String() string {
+	return strconv.FormatInt(a.Rate(), 10)
+}
+
+var _ Rater = (*ArrivalCounter)(nil)
@@ -0,0 +1,56 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArrivalCounter(t *testing.T) {
+	interval := 1 * time.Second
+	clock := newManualClock()
+	a := NewArrivalCounter(interval, WithClock(clock))
+
+	if gap := a.MeanGap(); gap != 0 {
+		t.Error("Expected ", gap, " to equal ", 0)
+	}
+
+	for i := 0; i < 5; i++ {
+		a.Incr(1)
+		clock.Add(20 * time.Millisecond)
+	}
+	clock.Add(100 * time.Millisecond)
+	a.Incr(1)
+
+	if val := a.Rate(); val != 6 {
+		t.Error("Expected ", val, " to equal ", 6)
+	}
+
+	// Gaps of 20ms, 20ms, 20ms, 20ms and 120ms
+	if gap := a.MeanGap(); gap != 40*time.Millisecond {
+		t.Error("Expected mean gap ", gap, " to equal ", 40*time.Millisecond)
+	}
+	if gap := a.GapPercentile(0.5); gap != 20*time.Millisecond {
+		t.Error("Expected median gap ", gap, " to equal ", 20*time.Millisecond)
+	}
+	if gap := a.GapPercentile(0.99); gap != 120*time.Millisecond {
+		t.Error("Expected p99 gap ", gap, " to equal ", 120*time.Millisecond)
+	}
+
+	clock.Add(2 * interval)
+	if gap := a.GapPercentile(0.99); gap != 0 {
+		t.Error("Expected ", gap, " to equal ", 0)
+	}
+	if gap := a.MeanGap(); gap != 0 {
+		t.Error("Expected ", gap, " to equal ", 0)
+	}
+}
+
+func TestArrivalCounter_InvalidQuantile(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Quantile > 1 did not panic")
+		}
+	}()
+
+	NewArrivalCounter(1 * time.Second).GapPercentile(2)
+}
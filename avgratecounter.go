@@ -51,6 +51,17 @@ func (a *AvgRateCounter) Rate() float64 {
 	return float64(value) / float64(hits)
 }
 
+// Average Returns the mean value passed to Incr during the last interval.
+// It is the same as Rate.
+func (a *AvgRateCounter) Average() float64 {
+	return a.Rate()
+}
+
+// Total returns the sum of the values passed to Incr during the last interval
+func (a *AvgRateCounter) Total() int64 {
+	return a.counter.Rate()
+}
+
 // Hits returns the number of calling method Incr during specified interval
 func (a *AvgRateCounter) Hits() int64 {
 	return a.hits.Rate()
@@ -126,3 +126,26 @@ func BenchmarkAvgRateCounter(b *testing.B) {
 		r.Rate()
 	}
 }
+
+func TestAvgRateCounter_AverageAndTotal(t *testing.T) {
+	interval := 500 * time.Millisecond
+	r := NewAvgRateCounter(interval)
+
+	if avg := r.Average(); avg != 0 {
+		t.Error("Expected ", avg, " to equal ", 0)
+	}
+
+	r.Incr(10)
+	r.Incr(20)
+	if avg := r.Average(); avg != 15 {
+		t.Error("Expected ", avg, " to equal ", 15)
+	}
+	if total := r.Total(); total != 30 {
+		t.Error("Expected ", total, " to equal ", 30)
+	}
+
+	time.Sleep(2 * interval)
+	if total := r.Total(); total != 0 {
+		t.Error("Expected ", total, " to equal ", 0)
+	}
+}
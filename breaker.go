@@ -0,0 +1,68 @@
+package ratecounter
+
+import "time"
+
+// A Breaker is a circuit breaker driven by the error rate over a sliding
+// window. It trips once enough requests have been seen and the share of
+// them that failed reaches a ratio, and closes again by itself as the
+// failures slide out of the window.
+type Breaker struct {
+	successes *RateCounter
+	failures  *RateCounter
+	ratio     float64
+	minVolume int64
+}
+
+// NewBreaker constructs a new Breaker which trips when at least ratio of
+// the requests in the last interval failed, once there were minVolume of
+// them or more. Its counters are built with opts.
+func NewBreaker(ratio float64, minVolume int64, intrvl time.Duration, opts ...Option) *Breaker {
+	if ratio <= 0 || ratio > 1 {
+		panic("Breaker ratio must be greater than 0 and at most 1")
+	}
+	if minVolume < 0 {
+		panic("Breaker minimum volume cannot be negative")
+	}
+
+	return &Breaker{
+		successes: NewRateCounter(intrvl, opts...),
+		failures:  NewRateCounter(intrvl, opts...),
+		ratio:     ratio,
+		minVolume: minVolume,
+	}
+}
+
+// Record Add the outcome of a request into the Breaker, a failure if err
+// is not nil
+func (b *Breaker) Record(err error) {
+	if err != nil {
+		b.failures.Incr(1)
+	} else {
+		b.successes.Incr(1)
+	}
+}
+
+// ErrorRate returns the share of the requests in the last interval which
+// failed, between 0 and 1, or 0 if there were none
+func (b *Breaker) ErrorRate() float64 {
+	failures, successes := rates(b.failures, b.successes)
+	return errorRate(failures, successes)
+}
+
+// Tripped reports whether the breaker is open, meaning requests should be
+// refused: there were at least the minimum volume of requests in the last
+// interval, and the error rate reached the ratio
+func (b *Breaker) Tripped() bool {
+	failures, successes := rates(b.failures, b.successes)
+	if failures+successes < b.minVolume {
+		return false
+	}
+	return errorRate(failures, successes) >= b.ratio
+}
+
+func errorRate(failures, successes int64) float64 {
+	if failures+successes == 0 {
+		return 0 // Avoid division by zero
+	}
+	return float64(failures) / float64(failures+successes)
+}
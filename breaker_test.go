@@ -0,0 +1,66 @@
+package ratecounter
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	clock := newManualClock()
+	b := NewBreaker(0.5, 4, 1*time.Second, WithClock(clock))
+	failed := errors.New("failed")
+
+	if b.Tripped() {
+		t.Error("Expected a new breaker to be closed")
+	}
+	if val := b.ErrorRate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+
+	// Too few requests to trip, however many fail
+	b.Record(failed)
+	b.Record(failed)
+	b.Record(failed)
+	if b.Tripped() {
+		t.Error("Expected the breaker to wait for the minimum volume")
+	}
+	if val := b.ErrorRate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+
+	b.Record(nil)
+	if !b.Tripped() {
+		t.Error("Expected the breaker to trip")
+	}
+	if val := b.ErrorRate(); val != 0.75 {
+		t.Error("Expected ", val, " to equal ", 0.75)
+	}
+
+	// Recovers once the failures leave the window
+	clock.Add(1100 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		b.Record(nil)
+	}
+	b.Record(failed)
+	if b.Tripped() {
+		t.Error("Expected the breaker to close again")
+	}
+	if val := b.ErrorRate(); val != 0.25 {
+		t.Error("Expected ", val, " to equal ", 0.25)
+	}
+}
+
+func TestNewBreaker_Invalid(t *testing.T) {
+	for _, ratio := range []float64{0, -0.5, 1.5} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Ratio %v did not panic", ratio)
+				}
+			}()
+
+			NewBreaker(ratio, 1, time.Second)
+		}()
+	}
+}
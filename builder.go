@@ -0,0 +1,41 @@
+package ratecounter
+
+import "time"
+
+// A Builder collects configuration for a RateCounter. Builders are values:
+// every method returns a new Builder and leaves the receiver unchanged, so
+// a partially configured Builder can be shared. Build hands out a
+// RateCounter which is frozen: its WithResolution method panics, so it
+// cannot be reconfigured while in use.
+//
+//	requests := ratecounter.NewBuilder(time.Minute).WithResolution(60).Build()
+type Builder struct {
+	interval time.Duration
+	opts     []Option
+}
+
+// NewBuilder constructs a new Builder for the interval provided
+func NewBuilder(intrvl time.Duration) Builder {
+	return Builder{interval: intrvl}
+}
+
+// With returns a Builder which also applies opts
+func (b Builder) With(opts ...Option) Builder {
+	combined := make([]Option, 0, len(b.opts)+len(opts))
+	combined = append(combined, b.opts...)
+	b.opts = append(combined, opts...)
+	return b
+}
+
+// WithResolution returns a Builder which sets the resolution. It panics if
+// resolution is less than 1.
+func (b Builder) WithResolution(resolution int) Builder {
+	return b.With(WithResolution(resolution))
+}
+
+// Build constructs a new, frozen RateCounter from the configuration
+func (b Builder) Build() *RateCounter {
+	r := NewRateCounter(b.interval, b.opts...)
+	r.frozen = true
+	return r
+}
@@ -0,0 +1,49 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuilder(t *testing.T) {
+	base := NewBuilder(1 * time.Second).With(WithUnit("requests"))
+	fine := base.WithResolution(100)
+	coarse := base.WithResolution(2)
+
+	r := fine.Build()
+	if len(r.partials) != 100 {
+		t.Error("Expected ", len(r.partials), " partials to equal ", 100)
+	}
+	if r.Unit() != "requests" {
+		t.Error("Expected ", r.Unit(), " to equal ", "requests")
+	}
+
+	// Deriving one builder does not change another
+	if r := coarse.Build(); len(r.partials) != 2 {
+		t.Error("Expected ", len(r.partials), " partials to equal ", 2)
+	}
+	if r := base.Build(); len(r.partials) != 20 {
+		t.Error("Expected ", len(r.partials), " partials to equal ", 20)
+	}
+
+	// Each Build returns a distinct counter
+	a, b := fine.Build(), fine.Build()
+	a.Incr(1)
+	if val := b.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestBuilder_Frozen(t *testing.T) {
+	r := NewBuilder(1 * time.Second).Build()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Reconfiguring a built counter did not panic")
+		}
+		if len(r.partials) != 20 {
+			t.Error("Expected ", len(r.partials), " partials to equal ", 20)
+		}
+	}()
+
+	r.WithResolution(2)
+}
@@ -0,0 +1,145 @@
+package ratecounter
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A Period is the calendar span a CalendarCounter counts over
+type Period int
+
+const (
+	// Day counts from local midnight to the next local midnight
+	Day Period = iota
+	// Week counts from local midnight on Monday to the following Monday
+	Week
+)
+
+// A CalendarCounter is a thread-safe counter which returns the number of
+// events in the current calendar day or week, in a given location. Period
+// boundaries follow the location's wall clock, so days are 23 or 25 hours
+// long across daylight saving transitions.
+type CalendarCounter struct {
+	period   Period
+	location *time.Location
+	// The bounds of the current period
+	start, end time.Time
+	current    int64
+	previous   int64
+	// Where the time comes from, in milliseconds, time.Now if nil
+	clock func() uint64
+	sync.Mutex
+}
+
+// NewCalendarCounter constructs a new CalendarCounter for the period in loc.
+// A nil loc means time.Local. Of the options, only WithClock applies; the
+// rest are ignored.
+func NewCalendarCounter(period Period, loc *time.Location, opts ...Option) *CalendarCounter {
+	if period != Day && period != Week {
+		panic("CalendarCounter period must be Day or Week")
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+
+	// Read the options from a counter which is never used
+	probe := &RateCounter{}
+	for _, opt := range opts {
+		opt(probe)
+	}
+
+	c := &CalendarCounter{
+		period:   period,
+		location: loc,
+		clock:    probe.clock,
+	}
+	c.start, c.end = c.bounds(c.now())
+
+	return c
+}
+
+// now returns the current time, from the clock if one is set
+func (c *CalendarCounter) now() time.Time {
+	return clockTime(c.clock)
+}
+
+// bounds returns the start and end of the period containing t
+func (c *CalendarCounter) bounds(t time.Time) (time.Time, time.Time) {
+	t = t.In(c.location)
+	year, month, day := t.Date()
+
+	if c.period == Week {
+		// Days since Monday
+		day -= (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day, 0, 0, 0, 0, c.location),
+			time.Date(year, month, day+7, 0, 0, 0, 0, c.location)
+	}
+
+	return time.Date(year, month, day, 0, 0, 0, 0, c.location),
+		time.Date(year, month, day+1, 0, 0, 0, 0, c.location)
+}
+
+// roll moves the counter on to the period containing now. The caller must
+// hold the lock.
+func (c *CalendarCounter) roll(now time.Time) {
+	if now.Before(c.end) {
+		return
+	}
+
+	start, end := c.bounds(now)
+	if start.Equal(c.end) {
+		c.previous = c.current
+	} else {
+		// At least one whole period passed without any calls
+		c.previous = 0
+	}
+	c.current = 0
+	c.start, c.end = start, end
+}
+
+func (c *CalendarCounter) incrAt(now time.Time, val int64) {
+	c.Lock()
+	c.roll(now)
+	c.current += val
+	c.Unlock()
+}
+
+func (c *CalendarCounter) rateAt(now time.Time) int64 {
+	c.Lock()
+	defer c.Unlock()
+	c.roll(now)
+	return c.current
+}
+
+// Incr Adds an event into the current period
+func (c *CalendarCounter) Incr(val int64) {
+	c.incrAt(c.now(), val)
+}
+
+// Rate Returns the number of events in the current period
+func (c *CalendarCounter) Rate() int64 {
+	return c.rateAt(c.now())
+}
+
+// Previous Returns the number of events in the period before the current one
+func (c *CalendarCounter) Previous() int64 {
+	c.Lock()
+	defer c.Unlock()
+	c.roll(c.now())
+	return c.previous
+}
+
+// Bounds returns the start and end of the current period
+func (c *CalendarCounter) Bounds() (time.Time, time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	c.roll(c.now())
+	return c.start, c.end
+}
+
+func (c *CalendarCounter) String() string {
+	return strconv.FormatInt(c.Rate(), 10)
+}
+
+var _ Rater = (*CalendarCounter)(nil)
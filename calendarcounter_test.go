@@ -0,0 +1,98 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalendarCounter(t *testing.T) {
+	clock := newManualClock()
+	c := NewCalendarCounter(Day, time.UTC, WithClock(clock))
+
+	c.Incr(1)
+	c.Incr(2)
+	if val := c.Rate(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+	if start, end := c.Bounds(); !start.Equal(time.Date(2018, time.October, 8, 0, 0, 0, 0, time.UTC)) || end.Sub(start) != 24*time.Hour {
+		t.Error("Expected ", start, " to ", end, " to be 8 October")
+	}
+
+	clock.Add(24 * time.Hour)
+	if val := c.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+	if val := c.Previous(); val != 3 {
+		t.Error("Expected previous ", val, " to equal ", 3)
+	}
+
+	// Skipping a whole day clears the previous period
+	c.Incr(1)
+	clock.Add(48 * time.Hour)
+	if val := c.Previous(); val != 0 {
+		t.Error("Expected previous ", val, " to equal ", 0)
+	}
+}
+
+func TestCalendarCounter_DST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone data unavailable:", err)
+	}
+
+	check := func(day time.Time, expected time.Duration) {
+		c := NewCalendarCounter(Day, loc)
+		start, end := c.bounds(day)
+		if start.Hour() != 0 || end.Hour() != 0 {
+			t.Error("Expected ", start, " and ", end, " to be midnight")
+		}
+		if end.Sub(start) != expected {
+			t.Error("Expected ", end.Sub(start), " to equal ", expected)
+		}
+	}
+
+	// Spring forward and fall back
+	check(time.Date(2018, time.March, 11, 12, 0, 0, 0, loc), 23*time.Hour)
+	check(time.Date(2018, time.November, 4, 12, 0, 0, 0, loc), 25*time.Hour)
+	check(time.Date(2018, time.June, 1, 12, 0, 0, 0, loc), 24*time.Hour)
+}
+
+func TestCalendarCounter_Week(t *testing.T) {
+	c := NewCalendarCounter(Week, time.UTC)
+
+	// Wednesday 10 October 2018
+	start, end := c.bounds(time.Date(2018, time.October, 10, 15, 0, 0, 0, time.UTC))
+	if !start.Equal(time.Date(2018, time.October, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Expected week to start on Monday, got", start)
+	}
+	if !end.Equal(time.Date(2018, time.October, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Expected week to end on the next Monday, got", end)
+	}
+
+	// A Sunday belongs to the week which started six days earlier
+	start, _ = c.bounds(time.Date(2018, time.October, 14, 23, 0, 0, 0, time.UTC))
+	if !start.Equal(time.Date(2018, time.October, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Expected week to start on Monday, got", start)
+	}
+}
+
+func TestCalendarCounter_Location(t *testing.T) {
+	loc := time.FixedZone("UTC+10", 10*60*60)
+	c := NewCalendarCounter(Day, loc)
+
+	// 20:00 UTC is already the next day in UTC+10
+	start, _ := c.bounds(time.Date(2018, time.October, 8, 20, 0, 0, 0, time.UTC))
+	if !start.Equal(time.Date(2018, time.October, 9, 0, 0, 0, 0, loc)) {
+		t.Error("Expected day to start at local midnight, got", start)
+	}
+}
+
+func TestCalendarCounter_InvalidPeriod(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Invalid period did not panic")
+		}
+	}()
+
+	NewCalendarCounter(Period(5), nil)
+}
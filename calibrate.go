@@ -0,0 +1,143 @@
+package ratecounter
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// A Calibration is the measured cost of the counter operations on the
+// current machine
+type Calibration struct {
+	// IncrCost is the average time taken by one Incr
+	IncrCost time.Duration
+	// RateCost is the average time taken by one Rate
+	RateCost time.Duration
+	// ParallelIncrCost is the average time taken by one Incr while every
+	// CPU increments the same counter
+	ParallelIncrCost time.Duration
+	// ShardedIncrCost is ParallelIncrCost for a counter built WithShards,
+	// with four shards per CPU
+	ShardedIncrCost time.Duration
+	// ClockCost is the average time taken to read the system clock, which
+	// every Incr does
+	ClockCost time.Duration
+	// MaxIncrsPerSecond is how many Incr calls a second one counter
+	// sustains under full contention
+	MaxIncrsPerSecond float64
+}
+
+// A Recommendation is the configuration Calibration.Recommend suggests for
+// a target call rate
+type Recommendation struct {
+	// Resolution is the number of partials to pass to WithResolution
+	Resolution int
+	// Shards is the number of shards to pass to WithShards, 0 for none
+	Shards int
+	// CachedClock reports whether reading the clock is enough of each Incr
+	// that a Clock which caches the time, e.g. refreshed by a ticker every
+	// millisecond, is worth passing to WithClock
+	CachedClock bool
+}
+
+// Calibrate measures Incr, Rate and the clock on the current machine,
+// spending roughly budget on each measurement
+func Calibrate(budget time.Duration) Calibration {
+	var c Calibration
+	procs := runtime.GOMAXPROCS(0)
+
+	c.IncrCost = measure(budget, 1, func(r *RateCounter) { r.Incr(1) })
+	c.RateCost = measure(budget, 1, func(r *RateCounter) { r.Rate() })
+	c.ParallelIncrCost = measure(budget, procs, func(r *RateCounter) { r.Incr(1) })
+	c.ShardedIncrCost = measure(budget, procs, func(r *RateCounter) { r.Incr(1) }, WithShards(calibrationShards()))
+	c.ClockCost = measure(budget, 1, func(r *RateCounter) { UnixMilli() })
+
+	if c.ParallelIncrCost > 0 {
+		c.MaxIncrsPerSecond = float64(time.Second) / float64(c.ParallelIncrCost)
+	}
+
+	return c
+}
+
+// calibrationShards returns the number of shards Calibrate measures and
+// Recommend suggests, a few times GOMAXPROCS
+func calibrationShards() int {
+	return 4 * runtime.GOMAXPROCS(0)
+}
+
+// measure returns the average wall time of op, run by workers goroutines on
+// a shared counter built with opts, until budget is spent
+func measure(budget time.Duration, workers int, op func(r *RateCounter), opts ...Option) time.Duration {
+	r := NewRateCounter(1*time.Second, opts...)
+	const batch = 1000
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		calls int
+	)
+	start := time.Now()
+	deadline := start.Add(budget)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n := 0
+			for time.Now().Before(deadline) {
+				for i := 0; i < batch; i++ {
+					op(r)
+				}
+				n += batch
+			}
+			mu.Lock()
+			calls += n
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if calls == 0 {
+		return 0
+	}
+	return time.Since(start) / time.Duration(calls)
+}
+
+// CPUFraction estimates the fraction of one CPU spent calling Incr at
+// callsPerSecond
+func (c Calibration) CPUFraction(callsPerSecond float64) float64 {
+	return callsPerSecond * float64(c.IncrCost) / float64(time.Second)
+}
+
+// Supports reports whether a single counter can sustain callsPerSecond Incr
+// calls under full contention, keeping half of the measured capacity spare
+func (c Calibration) Supports(callsPerSecond float64) bool {
+	return callsPerSecond <= c.MaxIncrsPerSecond/2
+}
+
+// Recommend suggests a configuration for a counter over intrvl receiving
+// callsPerSecond Incr calls, based on the measured costs:
+//   - the resolution is at most the default of 20, and no more than the
+//     events expected per interval, as partials which are mostly empty only
+//     make Rate slower
+//   - sharding is suggested when one counter cannot sustain the rate and
+//     sharding measured faster under contention
+//   - a cached clock is suggested when reading the clock is at least half
+//     the cost of an Incr and the calls take more than 1% of a CPU
+func (c Calibration) Recommend(intrvl time.Duration, callsPerSecond float64) Recommendation {
+	rec := Recommendation{Resolution: 20}
+
+	if events := callsPerSecond * intrvl.Seconds(); events < float64(rec.Resolution) {
+		rec.Resolution = int(events)
+		if rec.Resolution < 1 {
+			rec.Resolution = 1
+		}
+	}
+
+	if !c.Supports(callsPerSecond) && c.ShardedIncrCost > 0 && c.ShardedIncrCost < c.ParallelIncrCost {
+		rec.Shards = calibrationShards()
+	}
+
+	rec.CachedClock = 2*c.ClockCost >= c.IncrCost && c.CPUFraction(callsPerSecond) > 0.01
+
+	return rec
+}
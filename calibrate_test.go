@@ -0,0 +1,59 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalibrate(t *testing.T) {
+	c := Calibrate(20 * time.Millisecond)
+
+	if c.IncrCost <= 0 || c.RateCost <= 0 || c.ParallelIncrCost <= 0 || c.ShardedIncrCost <= 0 || c.ClockCost <= 0 {
+		t.Fatal("Expected positive costs, got", c)
+	}
+	if c.MaxIncrsPerSecond <= 0 {
+		t.Error("Expected ", c.MaxIncrsPerSecond, " to be positive")
+	}
+
+	if !c.Supports(1) {
+		t.Error("Expected one call a second to be supported")
+	}
+	if c.Supports(c.MaxIncrsPerSecond) {
+		t.Error("Expected full capacity to leave no headroom")
+	}
+
+	if val := c.CPUFraction(0); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestCalibration_Recommend(t *testing.T) {
+	c := Calibration{
+		IncrCost:          100 * time.Nanosecond,
+		ParallelIncrCost:  1 * time.Microsecond,
+		ShardedIncrCost:   200 * time.Nanosecond,
+		ClockCost:         60 * time.Nanosecond,
+		MaxIncrsPerSecond: 1e6,
+	}
+
+	// A quiet counter needs few partials, no shards and no cached clock
+	rec := c.Recommend(1*time.Second, 5)
+	if rec != (Recommendation{Resolution: 5}) {
+		t.Error("Expected ", rec, " to equal ", Recommendation{Resolution: 5})
+	}
+	if rec := c.Recommend(1*time.Second, 0.1); rec.Resolution != 1 {
+		t.Error("Expected ", rec.Resolution, " to equal ", 1)
+	}
+
+	// A busy one gets the default resolution, shards and a cached clock
+	rec = c.Recommend(1*time.Second, 1e6)
+	if rec.Resolution != 20 || rec.Shards != calibrationShards() || !rec.CachedClock {
+		t.Error("Expected ", rec, " to recommend shards and a cached clock")
+	}
+
+	// Sharding is not suggested where it measured no faster
+	c.ShardedIncrCost = c.ParallelIncrCost
+	if rec := c.Recommend(1*time.Second, 1e6); rec.Shards != 0 {
+		t.Error("Expected ", rec.Shards, " to equal ", 0)
+	}
+}
@@ -0,0 +1,44 @@
+package ratecounter
+
+import "time"
+
+// A Clock tells a counter the time. The default reads the system clock;
+// tests can supply their own to drive time manually.
+type Clock interface {
+	Now() time.Time
+}
+
+// WithClock reads the time from c instead of the system clock
+func WithClock(c Clock) Option {
+	return func(r *RateCounter) {
+		r.clock = func() uint64 {
+			return uint64(c.Now().UnixNano() / 1000000)
+		}
+	}
+}
+
+// clockTime returns the time clock reads, in milliseconds since the epoch,
+// or time.Now if clock is nil, for counters which keep times rather than
+// periods
+func clockTime(clock func() uint64) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+	return time.Unix(0, int64(clock())*int64(time.Millisecond))
+}
+
+// NewRateCounterWithClock Constructs a new RateCounter which reads the time
+// from c
+func NewRateCounterWithClock(intrvl time.Duration, c Clock) *RateCounter {
+	return NewRateCounter(intrvl, WithClock(c))
+}
+
+// NewAvgRateCounterWithClock constructs a new AvgRateCounter which reads the
+// time from c
+func NewAvgRateCounterWithClock(intrvl time.Duration, c Clock) *AvgRateCounter {
+	return &AvgRateCounter{
+		hits:     NewRateCounterWithClock(intrvl, c),
+		counter:  NewRateCounterWithClock(intrvl, c),
+		interval: intrvl,
+	}
+}
@@ -0,0 +1,91 @@
+package ratecounter
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock is a Clock which only moves when told to
+type manualClock struct {
+	now time.Time
+	sync.Mutex
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: time.Date(2018, time.October, 8, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *manualClock) Add(d time.Duration) {
+	c.Lock()
+	c.now = c.now.Add(d)
+	c.Unlock()
+}
+
+func TestRateCounterWithClock(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounterWithClock(500*time.Millisecond, clock)
+
+	check := func(expected int64) {
+		val := r.Rate()
+		if val != expected {
+			t.Error("Expected ", val, " to equal ", expected)
+		}
+	}
+
+	check(0)
+	r.Incr(1)
+	check(1)
+	clock.Add(400 * time.Millisecond)
+	r.Incr(2)
+	check(3)
+
+	// Time stands still until the clock moves
+	time.Sleep(10 * time.Millisecond)
+	check(3)
+
+	clock.Add(150 * time.Millisecond)
+	check(2)
+	clock.Add(time.Second)
+	check(0)
+}
+
+func TestAvgRateCounterWithClock(t *testing.T) {
+	clock := newManualClock()
+	r := NewAvgRateCounterWithClock(500*time.Millisecond, clock)
+
+	r.Incr(1)
+	clock.Add(50 * time.Millisecond)
+	r.Incr(3)
+	if rate := r.Rate(); rate != 2 {
+		t.Error("Expected ", rate, " to equal ", 2)
+	}
+
+	// Only the second event is still in the window
+	clock.Add(480 * time.Millisecond)
+	if rate, hits := r.Rate(), r.Hits(); rate != 3 || hits != 1 {
+		t.Error("Expected ", rate, " and ", hits, " to equal ", 3, " and ", 1)
+	}
+}
+
+func TestWithClock_Options(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounterWithOptions(200*time.Millisecond, WithResolution(2), WithClock(clock))
+
+	now := uint64(clock.Now().UnixNano() / 1000000)
+	if r.periodStart(r.period(now)) != now {
+		t.Error("Expected the first partial to start at the clock's time")
+	}
+
+	r.Incr(1)
+	clock.Add(time.Hour)
+	if val := r.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
@@ -0,0 +1,27 @@
+package ratecounter
+
+// Ratio returns the rate of a divided by the rate of b, e.g. errors over
+// requests or hits over lookups. Both counters are brought up to the same
+// instant before reading, so the result is not skewed by one of them
+// dropping a partial the other has not. It returns 0 when b is 0.
+func Ratio(a, b *RateCounter) float64 {
+	ra, rb := rates(a, b)
+
+	if rb == 0 {
+		return 0 // Avoid division by zero
+	}
+
+	return float64(ra) / float64(rb)
+}
+
+// Diff returns the rate of a minus the rate of b, with both counters
+// brought up to the same instant before reading
+func Diff(a, b *RateCounter) int64 {
+	ra, rb := rates(a, b)
+	return ra - rb
+}
+
+func rates(a, b *RateCounter) (int64, int64) {
+	now := a.now()
+	return a.rateAt(now), b.rateAt(now)
+}
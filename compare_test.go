@@ -0,0 +1,44 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRatio(t *testing.T) {
+	interval := 200 * time.Millisecond
+	errors := NewRateCounter(interval)
+	requests := NewRateCounter(interval)
+
+	if ratio := Ratio(errors, requests); ratio != 0 {
+		t.Error("Expected ", ratio, " to equal ", 0)
+	}
+
+	requests.Incr(4)
+	errors.Incr(1)
+	if ratio := Ratio(errors, requests); ratio != 0.25 {
+		t.Error("Expected ", ratio, " to equal ", 0.25)
+	}
+
+	time.Sleep(2 * interval)
+	if ratio := Ratio(errors, requests); ratio != 0 {
+		t.Error("Expected ", ratio, " to equal ", 0)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	interval := 200 * time.Millisecond
+	total := NewRateCounter(interval)
+	healthchecks := NewRateCounter(interval)
+
+	total.Incr(10)
+	healthchecks.Incr(3)
+	if diff := Diff(total, healthchecks); diff != 7 {
+		t.Error("Expected ", diff, " to equal ", 7)
+	}
+
+	time.Sleep(2 * interval)
+	if diff := Diff(total, healthchecks); diff != 0 {
+		t.Error("Expected ", diff, " to equal ", 0)
+	}
+}
@@ -0,0 +1,37 @@
+package ratecounter
+
+import "time"
+
+// ConvertRate converts a rate measured over one interval into the
+// equivalent rate over another, e.g. a count per minute into a count per
+// second. It assumes events are spread evenly across the measured interval,
+// so converting to a shorter interval gives an average rather than a peak,
+// and converting to a longer one extrapolates.
+func ConvertRate(rate float64, from, to time.Duration) float64 {
+	if from <= 0 {
+		panic("ConvertRate interval must be positive")
+	}
+
+	return rate * float64(to) / float64(from)
+}
+
+// RatePer Return the rate over the counter's interval converted to events
+// per d, regardless of the interval the counter was built with
+func (r *RateCounter) RatePer(d time.Duration) float64 {
+	return ConvertRate(float64(r.Rate()), time.Duration(r.interval)*time.Millisecond, d)
+}
+
+// RatePerSecond Return the rate converted to events per second
+func (r *RateCounter) RatePerSecond() float64 {
+	return r.RatePer(time.Second)
+}
+
+// PerMinute Return the rate converted to events per minute
+func (r *RateCounter) PerMinute() float64 {
+	return r.RatePer(time.Minute)
+}
+
+// PerHour Return the rate converted to events per hour
+func (r *RateCounter) PerHour() float64 {
+	return r.RatePer(time.Hour)
+}
@@ -0,0 +1,55 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConvertRate(t *testing.T) {
+	if val := ConvertRate(120, time.Minute, time.Second); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+	if val := ConvertRate(2, time.Second, time.Hour); val != 7200 {
+		t.Error("Expected ", val, " to equal ", 7200)
+	}
+	if val := ConvertRate(5, time.Second, time.Second); val != 5 {
+		t.Error("Expected ", val, " to equal ", 5)
+	}
+}
+
+func TestConvertRate_InvalidInterval(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Zero interval did not panic")
+		}
+	}()
+
+	ConvertRate(1, 0, time.Second)
+}
+
+func TestRateCounter_PerMinute(t *testing.T) {
+	r := NewRateCounter(10 * time.Second)
+	r.Incr(5)
+
+	if val := r.PerMinute(); val != 30 {
+		t.Error("Expected ", val, " to equal ", 30)
+	}
+	if val := r.PerHour(); val != 1800 {
+		t.Error("Expected ", val, " to equal ", 1800)
+	}
+}
+
+func TestRateCounter_RatePer(t *testing.T) {
+	r := NewRateCounter(1 * time.Minute)
+	r.Incr(120)
+
+	if val := r.RatePerSecond(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+	if val := r.RatePer(500 * time.Millisecond); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+	if val := r.RatePer(time.Minute); val != 120 {
+		t.Error("Expected ", val, " to equal ", 120)
+	}
+}
@@ -2,20 +2,24 @@ package ratecounter
 
 import "sync/atomic"
 
-// A Counter is a thread-safe counter implementation
-type Counter uint32
+// A Counter is a thread-safe counter implementation.
+//
+// A Counter is accessed with 64-bit atomic operations, so on 32-bit
+// platforms it must be 64-bit aligned: keep it as the first field of a
+// struct, or first among other 64-bit fields, or allocate it on its own.
+type Counter int64
 
 // Incr method increments the counter by some value
 func (c *Counter) Incr(val int64) {
-	atomic.AddUint32((*uint32)(c), uint32(val))
+	atomic.AddInt64((*int64)(c), val)
 }
 
 // Reset method resets the counter's value to zero
 func (c *Counter) Reset() {
-	atomic.StoreUint32((*uint32)(c), 0)
+	atomic.StoreInt64((*int64)(c), 0)
 }
 
 // Value method returns the counter's current value
 func (c *Counter) Value() int64 {
-	return int64(atomic.LoadUint32((*uint32)(c)))
+	return atomic.LoadInt64((*int64)(c))
 }
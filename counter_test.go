@@ -3,6 +3,7 @@ package ratecounter
 import (
 	"sync"
 	"testing"
+	"unsafe"
 )
 
 func TestCounter(t *testing.T) {
@@ -41,3 +42,41 @@ func BenchmarkCounter(b *testing.B) {
 		c.Incr(1)
 	}
 }
+
+func TestCounterLargeValues(t *testing.T) {
+	var c Counter
+
+	// Values beyond 32 bits are not truncated
+	c.Incr(1 << 40)
+	c.Incr(1 << 33)
+	if val := c.Value(); val != 1<<40+1<<33 {
+		t.Error("Expected ", val, " to equal ", int64(1<<40+1<<33))
+	}
+
+	c.Reset()
+	if val := c.Value(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestCounterNegative(t *testing.T) {
+	var c Counter
+
+	c.Incr(2)
+	c.Incr(-5)
+	if val := c.Value(); val != -3 {
+		t.Error("Expected ", val, " to equal ", -3)
+	}
+}
+
+func TestPartialAlignment(t *testing.T) {
+	// Partials sit in a slice and their stamps are accessed atomically, so
+	// each must be a multiple of 8 bytes to keep every stamp aligned on
+	// 32-bit platforms
+	if size := unsafe.Sizeof(partial{}); size%8 != 0 {
+		t.Error("Expected ", size, " to be a multiple of ", 8)
+	}
+	if offset := unsafe.Offsetof(partial{}.stamp); offset%8 != 0 {
+		t.Error("Expected ", offset, " to be a multiple of ", 8)
+	}
+}
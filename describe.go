@@ -0,0 +1,57 @@
+package ratecounter
+
+import "time"
+
+// A Kind says how a described counter's value is read
+type Kind int
+
+const (
+	// KindRate is a sum of the events in a rolling interval, read with Rate
+	KindRate Kind = iota + 1
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindRate:
+		return "rate"
+	}
+	return "unknown"
+}
+
+// A Description is machine-readable metadata about one counter, in the
+// spirit of runtime/metrics, for generic exporters and generated dashboards
+type Description struct {
+	// Name is the counter's name in the registry
+	Name string
+	// Unit is what the counter counts, e.g. "requests", empty if not set
+	Unit string
+	// Description is the counter's human readable description
+	Description string
+	// Kind is how the counter's value is read
+	Kind Kind
+	// Interval is the window the rate covers
+	Interval time.Duration
+	// Resolution is the number of partials the interval is split into
+	Resolution int
+	// Tags are the counter's dimensional tags
+	Tags map[string]string
+}
+
+// Describe returns a Description of every counter in the registry, in name
+// order
+func (g *Registry) Describe() []Description {
+	entries := g.entries()
+	descs := make([]Description, len(entries))
+	for i, e := range entries {
+		descs[i] = Description{
+			Name:        e.name,
+			Unit:        e.counter.Unit(),
+			Description: e.counter.Description(),
+			Kind:        KindRate,
+			Interval:    time.Duration(e.counter.interval) * time.Millisecond,
+			Resolution:  e.counter.resolution(),
+			Tags:        e.counter.Tags(),
+		}
+	}
+	return descs
+}
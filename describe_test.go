@@ -0,0 +1,28 @@
+package ratecounter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Describe(t *testing.T) {
+	g := NewRegistry(1*time.Minute, WithResolution(6), WithUnit("requests"), WithDescription("Requests served."), WithTags(map[string]string{"service": "api"}))
+	g.Get("GET /users")
+	g.Get("GET /orders")
+
+	expected := []Description{
+		{Name: "GET /orders", Unit: "requests", Description: "Requests served.", Kind: KindRate, Interval: time.Minute, Resolution: 6, Tags: map[string]string{"service": "api"}},
+		{Name: "GET /users", Unit: "requests", Description: "Requests served.", Kind: KindRate, Interval: time.Minute, Resolution: 6, Tags: map[string]string{"service": "api"}},
+	}
+	if descs := g.Describe(); !reflect.DeepEqual(descs, expected) {
+		t.Error("Expected ", descs, " to equal ", expected)
+	}
+
+	if descs := NewRegistry(time.Minute).Describe(); len(descs) != 0 {
+		t.Error("Expected ", descs, " to be empty")
+	}
+	if val := KindRate.String(); val != "rate" {
+		t.Error("Expected ", val, " to equal ", "rate")
+	}
+}
@@ -0,0 +1,52 @@
+package ratecounter
+
+import "sync"
+
+// A Downsampler folds the completed partials of a fine-grained RateCounter
+// into a coarse one, so a short, high resolution window can feed a long
+// history cheaply. For example a one second counter can be folded into a
+// one hour counter.
+//
+// Fold must be called at least once per fine interval, typically from a
+// time.Ticker, or partials which expire between calls are lost.
+type Downsampler struct {
+	fine   *RateCounter
+	coarse *RateCounter
+	// The fine counter's last period which has been folded
+	folded uint64
+	sync.Mutex
+}
+
+// NewDownsampler constructs a new Downsampler from fine into coarse
+func NewDownsampler(fine, coarse *RateCounter) *Downsampler {
+	return &Downsampler{
+		fine:   fine,
+		coarse: coarse,
+		// The current period is folded once it completes
+		folded: fine.advance(fine.now()) - 1,
+	}
+}
+
+// Fold adds every fine partial completed since the last call into the
+// coarse counter, and returns the value folded
+func (d *Downsampler) Fold() int64 {
+	d.Lock()
+	defer d.Unlock()
+
+	e := d.fine.advance(d.fine.now())
+
+	// The current partial is still filling, and only the periods still in
+	// the window are around
+	from := d.folded + 1
+	if oldest := before(e+1, uint64(d.fine.resolution())); from < oldest {
+		from = oldest
+	}
+	sum := d.fine.sum(from, e-1)
+	d.folded = e - 1
+
+	if sum != 0 {
+		d.coarse.Incr(sum)
+	}
+
+	return sum
+}
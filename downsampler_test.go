@@ -0,0 +1,44 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDownsampler(t *testing.T) {
+	fine := NewRateCounter(200 * time.Millisecond).WithResolution(4)
+	coarse := NewRateCounter(1 * time.Hour)
+	d := NewDownsampler(fine, coarse)
+
+	fine.Incr(1)
+	fine.Incr(2)
+
+	// Nothing has completed yet
+	if val := d.Fold(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	fine.Incr(4)
+	if val := d.Fold(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if val := d.Fold(); val != 4 {
+		t.Error("Expected ", val, " to equal ", 4)
+	}
+
+	// Folded partials are not counted twice
+	if val := d.Fold(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+
+	time.Sleep(400 * time.Millisecond)
+	if val := fine.Rate(); val != 0 {
+		t.Error("Expected fine rate ", val, " to equal ", 0)
+	}
+	if val := coarse.Rate(); val != 7 {
+		t.Error("Expected coarse rate ", val, " to equal ", 7)
+	}
+}
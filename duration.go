@@ -0,0 +1,42 @@
+package ratecounter
+
+import "time"
+
+// ObserveDuration Add an operation which took d into the AvgRateCounter,
+// in nanoseconds, for use as a latency meter
+func (a *AvgRateCounter) ObserveDuration(d time.Duration) {
+	a.Incr(d.Nanoseconds())
+}
+
+// AvgDuration Returns the mean duration passed to ObserveDuration during
+// the last interval
+func (a *AvgRateCounter) AvgDuration() time.Duration {
+	return time.Duration(a.Rate())
+}
+
+// ObserveDuration Add an operation which took d into the PercentileCounter,
+// in nanoseconds. The bucket bounds should be in nanoseconds too, e.g. from
+// DurationBounds.
+func (p *PercentileCounter) ObserveDuration(d time.Duration) {
+	p.Observe(float64(d.Nanoseconds()))
+}
+
+// PercentileDuration Return an estimate of the q-th quantile of the
+// durations observed in the last interval, e.g. PercentileDuration(0.99)
+// for p99 latency
+func (p *PercentileCounter) PercentileDuration(q float64) time.Duration {
+	return time.Duration(p.Percentile(q))
+}
+
+// P99Duration Return an estimate of the 99th percentile of the durations
+// observed in the last interval
+func (p *PercentileCounter) P99Duration() time.Duration {
+	return p.PercentileDuration(0.99)
+}
+
+// DurationBounds returns count bucket bounds, in nanoseconds, starting at
+// start and each factor times the last, for PercentileCounters fed with
+// ObserveDuration
+func DurationBounds(start time.Duration, factor float64, count int) []float64 {
+	return ExponentialBounds(float64(start.Nanoseconds()), factor, count)
+}
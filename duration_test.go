@@ -0,0 +1,44 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAvgRateCounter_AvgDuration(t *testing.T) {
+	a := NewAvgRateCounter(1 * time.Second)
+
+	if val := a.AvgDuration(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+
+	a.ObserveDuration(10 * time.Millisecond)
+	a.ObserveDuration(30 * time.Millisecond)
+	if val := a.AvgDuration(); val != 20*time.Millisecond {
+		t.Error("Expected ", val, " to equal ", 20*time.Millisecond)
+	}
+}
+
+func TestPercentileCounter_PercentileDuration(t *testing.T) {
+	p := NewPercentileCounter(1*time.Second, DurationBounds(1*time.Millisecond, 2, 8))
+
+	for i := 0; i < 99; i++ {
+		p.ObserveDuration(3 * time.Millisecond)
+	}
+	p.ObserveDuration(100 * time.Millisecond)
+
+	// The bulk falls in the 2ms to 4ms bucket and the outlier in the 64ms
+	// to 128ms one
+	if val := p.PercentileDuration(0.5); val <= 2*time.Millisecond || val > 4*time.Millisecond {
+		t.Error("Expected ", val, " to be between ", 2*time.Millisecond, " and ", 4*time.Millisecond)
+	}
+	if val := p.PercentileDuration(1); val != 128*time.Millisecond {
+		t.Error("Expected ", val, " to equal ", 128*time.Millisecond)
+	}
+	if val := p.P99Duration(); val != p.PercentileDuration(0.99) {
+		t.Error("Expected ", val, " to equal ", p.PercentileDuration(0.99))
+	}
+	if val := p.P99Duration(); val > 4*time.Millisecond {
+		t.Error("Expected ", val, " to be at most ", 4*time.Millisecond)
+	}
+}
@@ -0,0 +1,26 @@
+package ratecounter
+
+import "errors"
+
+// Errors returned by the package, so callers can branch on the failure with
+// errors.Is. Constructors and options panic on invalid configuration
+// instead, as they always have, since it is a programming error; validate
+// values read from configuration before passing them in.
+var (
+	// ErrInvalidInterval means an interval was zero or negative
+	ErrInvalidInterval = errors.New("ratecounter: interval must be positive")
+	// ErrInvalidResolution means a resolution was less than 1, or an encoded
+	// one was too large or did not match its buckets
+	ErrInvalidResolution = errors.New("ratecounter: invalid resolution")
+	// ErrInvalidSampleRate means an encoded sample rate was not greater than
+	// 0 and at most 1
+	ErrInvalidSampleRate = errors.New("ratecounter: sample rate must be greater than 0 and at most 1")
+	// ErrMissingCounters means an encoded AvgRateCounter lacked its hit or
+	// value counter
+	ErrMissingCounters = errors.New("ratecounter: missing hits or values")
+	// ErrSnapshotVersion means an encoded counter was written by a newer
+	// version of the package, in a form this one cannot read
+	ErrSnapshotVersion = errors.New("ratecounter: unsupported snapshot version")
+	// ErrClosed means something was used after it was closed
+	ErrClosed = errors.New("ratecounter: closed")
+)
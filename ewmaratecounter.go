@@ -0,0 +1,139 @@
+package ratecounter
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// An EWMARateCounter is a thread-safe counter which returns an
+// exponentially weighted moving average of the event rate, like Unix load
+// averages or Dropwizard meters. It moves smoothly rather than jumping as
+// partials drop, which suits dashboards.
+//
+// Events are counted as they arrive and folded into the average once per
+// tick. Ticks are caught up lazily on the next Incr or Rate, so an idle
+// counter decays correctly without a background goroutine.
+type EWMARateCounter struct {
+	// Accessed atomically, so kept first for 64-bit alignment
+	uncounted Counter
+	// When the last tick was folded in, in milliseconds
+	lastTick uint64
+	// The average events per second
+	rate        float64
+	initialized bool
+	alpha       float64
+	tick        uint32
+	// Where the time comes from, in milliseconds, UnixMilli if nil
+	clock func() uint64
+	sync.Mutex
+}
+
+// The tick used by the presets, as in Unix load averages
+const ewmaTick = 5 * time.Second
+
+// NewEWMARateCounter Constructs a new EWMARateCounter which folds the
+// events of each tick into the average with weight alpha, between 0 and 1
+func NewEWMARateCounter(alpha float64, tick time.Duration) *EWMARateCounter {
+	if alpha <= 0 || alpha > 1 {
+		panic("EWMARateCounter alpha must be in (0, 1]")
+	}
+	if tick < time.Millisecond {
+		panic("EWMARateCounter tick must be at least a millisecond")
+	}
+
+	return &EWMARateCounter{
+		lastTick: UnixMilli(),
+		alpha:    alpha,
+		tick:     uint32(tick.Nanoseconds() / 1000000),
+	}
+}
+
+// NewEWMARateCounterOver Constructs a new EWMARateCounter averaging over
+// roughly the period given, ticking every five seconds
+func NewEWMARateCounterOver(period time.Duration) *EWMARateCounter {
+	return NewEWMARateCounter(1-math.Exp(-ewmaTick.Seconds()/period.Seconds()), ewmaTick)
+}
+
+// NewEWMA1 Constructs a new EWMARateCounter averaging over one minute
+func NewEWMA1() *EWMARateCounter {
+	return NewEWMARateCounterOver(1 * time.Minute)
+}
+
+// NewEWMA5 Constructs a new EWMARateCounter averaging over five minutes
+func NewEWMA5() *EWMARateCounter {
+	return NewEWMARateCounterOver(5 * time.Minute)
+}
+
+// NewEWMA15 Constructs a new EWMARateCounter averaging over fifteen minutes
+func NewEWMA15() *EWMARateCounter {
+	return NewEWMARateCounterOver(15 * time.Minute)
+}
+
+func (e *EWMARateCounter) now() uint64 {
+	if e.clock != nil {
+		return e.clock()
+	}
+	return UnixMilli()
+}
+
+// update folds in every tick which has ended
+func (e *EWMARateCounter) update() {
+	now := e.now()
+	if now-atomic.LoadUint64(&e.lastTick) < uint64(e.tick) {
+		return
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	lastTick := atomic.LoadUint64(&e.lastTick)
+	if now < lastTick {
+		return
+	}
+	ticks := (now - lastTick) / uint64(e.tick)
+	if ticks == 0 {
+		// Someone else has already folded them in
+		return
+	}
+
+	// The events so far all arrived in the first tick to end
+	instant := float64(atomic.SwapInt64((*int64)(&e.uncounted), 0)) / (float64(e.tick) / 1000)
+	if !e.initialized {
+		e.rate = instant
+		e.initialized = true
+	} else {
+		e.rate += e.alpha * (instant - e.rate)
+	}
+	// The ticks after it were empty
+	e.rate *= math.Pow(1-e.alpha, float64(ticks-1))
+
+	atomic.StoreUint64(&e.lastTick, lastTick+ticks*uint64(e.tick))
+}
+
+// Incr Add an event into the EWMARateCounter
+func (e *EWMARateCounter) Incr(val int64) {
+	e.update()
+	e.uncounted.Incr(val)
+}
+
+// RatePerSecond Return the average number of events per second, as of the
+// last tick
+func (e *EWMARateCounter) RatePerSecond() float64 {
+	e.update()
+
+	e.Lock()
+	defer e.Unlock()
+	return e.rate
+}
+
+// Rate Return the average number of events per second, rounded
+func (e *EWMARateCounter) Rate() int64 {
+	return int64(math.Round(e.RatePerSecond()))
+}
+
+func (e *EWMARateCounter) String() string {
+	return strconv.FormatFloat(e.RatePerSecond(), 'e', 5, 64)
+}
@@ -0,0 +1,94 @@
+package ratecounter
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func newTestEWMA(alpha float64, tick time.Duration, clock *manualClock) *EWMARateCounter {
+	e := NewEWMARateCounter(alpha, tick)
+	e.clock = func() uint64 {
+		return uint64(clock.Now().UnixNano() / 1000000)
+	}
+	e.lastTick = e.clock()
+	return e
+}
+
+func TestEWMARateCounter(t *testing.T) {
+	clock := newManualClock()
+	e := newTestEWMA(0.5, 1*time.Second, clock)
+
+	e.Incr(10)
+	if val := e.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0, " before the first tick")
+	}
+
+	// The first tick seeds the average
+	clock.Add(1 * time.Second)
+	if val := e.RatePerSecond(); val != 10 {
+		t.Error("Expected ", val, " to equal ", 10)
+	}
+
+	e.Incr(20)
+	clock.Add(1 * time.Second)
+	if val := e.RatePerSecond(); val != 15 {
+		t.Error("Expected ", val, " to equal ", 15)
+	}
+
+	// Idle ticks are caught up on the next read
+	clock.Add(3 * time.Second)
+	if val := e.RatePerSecond(); val != 1.875 {
+		t.Error("Expected ", val, " to equal ", 1.875)
+	}
+	if val := e.Rate(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+}
+
+func TestEWMARateCounter_Presets(t *testing.T) {
+	for _, tc := range []struct {
+		e      *EWMARateCounter
+		period float64
+	}{
+		{NewEWMA1(), 60},
+		{NewEWMA5(), 300},
+		{NewEWMA15(), 900},
+	} {
+		if expected := 1 - math.Exp(-5/tc.period); tc.e.alpha != expected {
+			t.Error("Expected ", tc.e.alpha, " to equal ", expected)
+		}
+		if tc.e.tick != 5000 {
+			t.Error("Expected ", tc.e.tick, " to equal ", 5000)
+		}
+	}
+}
+
+func TestEWMARateCounter_Smooth(t *testing.T) {
+	clock := newManualClock()
+	e := newTestEWMA(1-math.Exp(-5.0/60), ewmaTick, clock)
+
+	// A steady rate is tracked exactly, and a burst only nudges it
+	for i := 0; i < 20; i++ {
+		e.Incr(50)
+		clock.Add(ewmaTick)
+	}
+	if val := e.Rate(); val != 10 {
+		t.Error("Expected ", val, " to equal ", 10)
+	}
+
+	e.Incr(5000)
+	clock.Add(ewmaTick)
+	if val := e.RatePerSecond(); val < 50 || val > 150 {
+		t.Error("Expected ", val, " to be near ", 90)
+	}
+}
+
+func BenchmarkEWMARateCounter(b *testing.B) {
+	e := NewEWMA1()
+
+	for i := 0; i < b.N; i++ {
+		e.Incr(1)
+		e.Rate()
+	}
+}
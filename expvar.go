@@ -0,0 +1,12 @@
+package ratecounter
+
+import "expvar"
+
+// Publish registers rc with expvar under name, so its rate shows up under
+// /debug/vars. The rate is read lazily, each time the variables are
+// served. Like expvar.Publish, it panics if name is already registered.
+func Publish(name string, rc *RateCounter) {
+	// String returns the rate as a JSON number, so rc is already an
+	// expvar.Var
+	expvar.Publish(name, rc)
+}
@@ -0,0 +1,25 @@
+package ratecounter
+
+import (
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestPublish(t *testing.T) {
+	r := NewRateCounter(1 * time.Second)
+	Publish("ratecounter_test_requests", r)
+
+	v := expvar.Get("ratecounter_test_requests")
+	if v == nil {
+		t.Fatal("Expected the counter to be published")
+	}
+	if s := v.String(); s != "0" {
+		t.Error("Expected ", s, " to equal ", "0")
+	}
+
+	r.Incr(3)
+	if s := v.String(); s != "3" {
+		t.Error("Expected ", s, " to equal ", "3")
+	}
+}
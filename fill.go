@@ -0,0 +1,64 @@
+package ratecounter
+
+import "reflect"
+
+// Fill sets the fields of the struct dst points to from the rates of the
+// counters named prefix plus each field's ratecounter tag, all read at the
+// same instant, so a handler can return typed stats:
+//
+//	type Stats struct {
+//		Users  int64 `ratecounter:"users"`
+//		Orders int64 `ratecounter:"orders"`
+//		DB     struct {
+//			Queries int64 `ratecounter:"queries"`
+//		} `ratecounter:"db."`
+//	}
+//	var s Stats
+//	g.Fill("api.", &s) // Users from "api.users", DB.Queries from "api.db.queries"
+//
+// Tagged fields must be integers, floats or structs, which are filled with
+// their tag added to the prefix. Untagged fields are left alone, and fields
+// of counters which do not exist are set to zero, without creating them.
+// It panics if dst is not a pointer to a struct.
+func (g *Registry) Fill(prefix string, dst interface{}) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		panic("Registry Fill needs a pointer to a struct")
+	}
+
+	fill(g.ConsistentSnapshot().Counters, prefix, v.Elem())
+}
+
+// fill sets the tagged fields of the struct v from the rates of the
+// counters named prefix plus their tags
+func fill(counters map[string]Snapshot, prefix string, v reflect.Value) {
+	t := v.Type()
+	for ii := 0; ii < t.NumField(); ii++ {
+		name, ok := t.Field(ii).Tag.Lookup("ratecounter")
+		if !ok {
+			continue
+		}
+
+		f := v.Field(ii)
+		if !f.CanSet() {
+			panic("Registry Fill cannot set unexported field " + t.Field(ii).Name)
+		}
+
+		rate := counters[prefix+name].Rate
+		switch f.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f.SetInt(rate)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if rate < 0 {
+				rate = 0
+			}
+			f.SetUint(uint64(rate))
+		case reflect.Float32, reflect.Float64:
+			f.SetFloat(float64(rate))
+		case reflect.Struct:
+			fill(counters, prefix+name, f)
+		default:
+			panic("Registry Fill cannot set field " + t.Field(ii).Name + " of type " + f.Type().String())
+		}
+	}
+}
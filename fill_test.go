@@ -0,0 +1,59 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegistry_Fill(t *testing.T) {
+	g := NewRegistry(1 * time.Second)
+	g.Get("api.users").Incr(3)
+	g.Get("api.db.queries").Incr(7)
+	g.Get("web.users").Incr(100)
+
+	var s struct {
+		Users  int64   `ratecounter:"users"`
+		Orders uint32  `ratecounter:"orders"`
+		Ratio  float64 `ratecounter:"users"`
+		Other  int64
+		DB     struct {
+			Queries int `ratecounter:"queries"`
+		} `ratecounter:"db."`
+	}
+	s.Other = 5
+	g.Fill("api.", &s)
+
+	if s.Users != 3 {
+		t.Error("Expected ", s.Users, " to equal ", 3)
+	}
+	if s.Orders != 0 {
+		t.Error("Expected ", s.Orders, " to equal ", 0)
+	}
+	if s.Ratio != 3 {
+		t.Error("Expected ", s.Ratio, " to equal ", 3)
+	}
+	if s.Other != 5 {
+		t.Error("Expected ", s.Other, " to equal ", 5)
+	}
+	if s.DB.Queries != 7 {
+		t.Error("Expected ", s.DB.Queries, " to equal ", 7)
+	}
+
+	// Missing counters are not created
+	if _, ok := g.Lookup("api.orders"); ok {
+		t.Error("Expected api.orders not to be created")
+	}
+}
+
+func TestRegistry_FillNotStruct(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a non-pointer to panic")
+		}
+	}()
+
+	var s struct {
+		Users int64 `ratecounter:"users"`
+	}
+	NewRegistry(1*time.Second).Fill("", s)
+}
@@ -0,0 +1,163 @@
+package ratecounter
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// The version of the JSON form MarshalJSON writes. UnmarshalJSON accepts it
+// and earlier ones, treating a missing version as 0.
+const jsonVersion = 1
+
+// The largest resolution UnmarshalJSON accepts without buckets, so a small
+// document cannot allocate an arbitrary number of partials
+const maxJSONResolution = 1 << 16
+
+// jsonRateCounter is the JSON form of a RateCounter
+type jsonRateCounter struct {
+	Version     int               `json:"version"`
+	Rate        int64             `json:"rate"`
+	Interval    string            `json:"interval"`
+	Resolution  int               `json:"resolution"`
+	Buckets     []int64           `json:"buckets,omitempty"`
+	Unit        string            `json:"unit,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	SampleRate  float64           `json:"sample_rate,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding the current rate, the
+// interval and resolution, the value of each partial and the sample rate so
+// the counter can be restored with UnmarshalJSON
+func (r *RateCounter) MarshalJSON() ([]byte, error) {
+	s := r.Snapshot()
+
+	return json.Marshal(jsonRateCounter{
+		Version:     jsonVersion,
+		Rate:        s.Rate,
+		Interval:    s.Interval.String(),
+		Resolution:  len(s.Buckets),
+		Buckets:     s.Buckets,
+		Unit:        s.Unit,
+		Description: s.Description,
+		Tags:        s.Tags,
+		SampleRate:  r.sampleRate,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, restoring a counter encoded by
+// MarshalJSON. The restored partials count from now. If there are no
+// buckets the whole rate is put in the current partial, and the resolution
+// may be at most 65536; otherwise it must match the number of buckets. It
+// must not be called on a counter which is in use.
+func (r *RateCounter) UnmarshalJSON(data []byte) error {
+	var j jsonRateCounter
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if j.Version > jsonVersion {
+		return ErrSnapshotVersion
+	}
+
+	interval, err := time.ParseDuration(j.Interval)
+	if err != nil {
+		return err
+	}
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+
+	resolution := j.Resolution
+	if len(j.Buckets) > 0 {
+		if resolution != 0 && resolution != len(j.Buckets) {
+			return ErrInvalidResolution
+		}
+		resolution = len(j.Buckets)
+	} else if resolution > maxJSONResolution {
+		return ErrInvalidResolution
+	}
+	if resolution < 1 {
+		return ErrInvalidResolution
+	}
+	if j.SampleRate < 0 || j.SampleRate > 1 {
+		return ErrInvalidSampleRate
+	}
+
+	r.interval = uint32(interval.Nanoseconds() / 1000000)
+	r.partials = r.newPartials(resolution)
+	r.unit, r.description, r.tags = j.Unit, j.Description, j.Tags
+	r.sampleRate = j.SampleRate
+
+	r.setOrigin(r.now())
+	e := r.epoch
+
+	restore := func(period uint64, val int64) {
+		p := &r.partials[period%uint64(len(r.partials))]
+		p.stamp = period
+		p.Incr(val)
+	}
+	if len(j.Buckets) > 0 {
+		// Oldest first, ending with the current partial
+		for i, b := range j.Buckets {
+			restore(e+1-uint64(resolution)+uint64(i), b)
+		}
+	} else if r.sampleRate > 0 {
+		// The rate was scaled up from the sampled events
+		restore(e, int64(math.Round(float64(j.Rate)*r.sampleRate)))
+	} else {
+		restore(e, j.Rate)
+	}
+
+	return nil
+}
+
+// jsonAvgRateCounter is the JSON form of an AvgRateCounter
+type jsonAvgRateCounter struct {
+	Version    int          `json:"version"`
+	Rate       float64      `json:"rate"`
+	Interval   string       `json:"interval"`
+	Resolution int          `json:"resolution"`
+	Hits       *RateCounter `json:"hits"`
+	Values     *RateCounter `json:"values"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding the current average, the
+// interval and resolution, and the underlying hit and value counters
+func (a *AvgRateCounter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAvgRateCounter{
+		Version:    jsonVersion,
+		Rate:       a.Rate(),
+		Interval:   a.interval.String(),
+		Resolution: len(a.hits.partials),
+		Hits:       a.hits,
+		Values:     a.counter,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, restoring a counter encoded by
+// MarshalJSON. It must not be called on a counter which is in use.
+func (a *AvgRateCounter) UnmarshalJSON(data []byte) error {
+	j := jsonAvgRateCounter{
+		Hits:   &RateCounter{},
+		Values: &RateCounter{},
+	}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if j.Version > jsonVersion {
+		return ErrSnapshotVersion
+	}
+
+	interval, err := time.ParseDuration(j.Interval)
+	if err != nil {
+		return err
+	}
+	if j.Hits.partials == nil || j.Values.partials == nil {
+		return ErrMissingCounters
+	}
+
+	a.hits, a.counter, a.interval = j.Hits, j.Values, interval
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package ratecounter
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRateCounter_MarshalJSON(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounterWithOptions(1*time.Second, WithResolution(4), WithClock(clock), WithUnit("requests"))
+	r.Incr(1)
+	clock.Add(300 * time.Millisecond)
+	r.Incr(2)
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"version":1,"rate":3,"interval":"1s","resolution":4,"buckets":[0,0,1,2],"unit":"requests"}`
+	if string(data) != expected {
+		t.Error("Expected ", string(data), " to equal ", expected)
+	}
+
+	var restored RateCounter
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if val := restored.Rate(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+	if buckets := restored.Buckets(); !reflect.DeepEqual(buckets, []int64{0, 0, 1, 2}) {
+		t.Error("Expected ", buckets, " to equal ", []int64{0, 0, 1, 2})
+	}
+	if restored.Unit() != "requests" {
+		t.Error("Expected ", restored.Unit(), " to equal ", "requests")
+	}
+
+	// The restored counter keeps counting
+	restored.Incr(4)
+	if val := restored.Rate(); val != 7 {
+		t.Error("Expected ", val, " to equal ", 7)
+	}
+}
+
+func TestRateCounter_UnmarshalJSON_RateOnly(t *testing.T) {
+	var r RateCounter
+	if err := json.Unmarshal([]byte(`{"rate":5,"interval":"1m","resolution":10}`), &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if val := r.Rate(); val != 5 {
+		t.Error("Expected ", val, " to equal ", 5)
+	}
+	if len(r.partials) != 10 {
+		t.Error("Expected ", len(r.partials), " partials to equal ", 10)
+	}
+}
+
+func TestRateCounter_MarshalJSON_SampleRate(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounterWithOptions(1*time.Second, WithResolution(2), WithClock(clock), WithSampleRate(0.5))
+	r.Incr(1)
+	r.Incr(1)
+	r.Incr(1)
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var restored RateCounter
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if val := restored.Rate(); val != r.Rate() {
+		t.Error("Expected ", val, " to equal ", r.Rate())
+	}
+
+	// Without buckets the scaled rate is turned back into sampled events
+	var rateOnly RateCounter
+	if err := json.Unmarshal([]byte(`{"rate":6,"interval":"1s","resolution":2,"sample_rate":0.5}`), &rateOnly); err != nil {
+		t.Fatal(err)
+	}
+	if val := rateOnly.Rate(); val != 6 {
+		t.Error("Expected ", val, " to equal ", 6)
+	}
+	if buckets := rateOnly.Buckets(); !reflect.DeepEqual(buckets, []int64{0, 3}) {
+		t.Error("Expected ", buckets, " to equal ", []int64{0, 3})
+	}
+}
+
+func TestRateCounter_UnmarshalJSON_Invalid(t *testing.T) {
+	for _, data := range []string{
+		`{"rate":1,"interval":"nope","resolution":1}`,
+		`{"rate":1,"interval":"0s","resolution":1}`,
+		`{"rate":1,"interval":"1s","resolution":0}`,
+		`[]`,
+	} {
+		var r RateCounter
+		if err := json.Unmarshal([]byte(data), &r); err == nil {
+			t.Error("Expected an error for ", data)
+		}
+	}
+}
+
+func TestUnmarshalJSON_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		data string
+		err  error
+	}{
+		{`{"rate":1,"interval":"-1s","resolution":1}`, ErrInvalidInterval},
+		{`{"rate":1,"interval":"1s","resolution":0}`, ErrInvalidResolution},
+		{`{"rate":1,"interval":"1s","resolution":1000000000}`, ErrInvalidResolution},
+		{`{"rate":1,"interval":"1s","resolution":1000000000,"buckets":[1]}`, ErrInvalidResolution},
+		{`{"rate":1,"interval":"1s","resolution":1,"sample_rate":2}`, ErrInvalidSampleRate},
+		{`{"version":2,"rate":1,"interval":"1s","resolution":1}`, ErrSnapshotVersion},
+	} {
+		var r RateCounter
+		if err := json.Unmarshal([]byte(tc.data), &r); !errors.Is(err, tc.err) {
+			t.Error("Expected ", err, " to be ", tc.err, " for ", tc.data)
+		}
+	}
+
+	var a AvgRateCounter
+	if err := json.Unmarshal([]byte(`{"interval":"1s"}`), &a); !errors.Is(err, ErrMissingCounters) {
+		t.Error("Expected ", err, " to be ", ErrMissingCounters)
+	}
+	if err := json.Unmarshal([]byte(`{"version":2,"interval":"1s"}`), &a); !errors.Is(err, ErrSnapshotVersion) {
+		t.Error("Expected ", err, " to be ", ErrSnapshotVersion)
+	}
+}
+
+func TestAvgRateCounter_MarshalJSON(t *testing.T) {
+	r := NewAvgRateCounter(1 * time.Second).WithResolution(2)
+	r.Incr(1)
+	r.Incr(3)
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["rate"] != 2.0 || decoded["interval"] != "1s" || decoded["resolution"] != 2.0 {
+		t.Error("Unexpected encoding", string(data))
+	}
+
+	var restored AvgRateCounter
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if rate, hits := restored.Rate(), restored.Hits(); rate != 2 || hits != 2 {
+		t.Error("Expected ", rate, " and ", hits, " to equal ", 2, " and ", 2)
+	}
+}
@@ -0,0 +1,39 @@
+package ratecounter
+
+// A LocalCounter accumulates increments for a RateCounter without atomics,
+// and adds them to it in one go when flushed. It is not thread-safe: use it
+// from a single goroutine, e.g. for the lifetime of one request, to cut the
+// atomic operations of chatty handlers.
+//
+//	local := counter.Local()
+//	defer local.Flush()
+type LocalCounter struct {
+	parent *RateCounter
+	value  int64
+}
+
+// Local returns a LocalCounter which flushes into the RateCounter
+func (r *RateCounter) Local() LocalCounter {
+	return LocalCounter{parent: r}
+}
+
+// Incr adds val to the local total
+func (l *LocalCounter) Incr(val int64) {
+	l.value += val
+}
+
+// Value returns the local total not yet flushed
+func (l *LocalCounter) Value() int64 {
+	return l.value
+}
+
+// Flush adds the local total to the RateCounter and zeroes it. Flushing an
+// empty LocalCounter does nothing.
+func (l *LocalCounter) Flush() {
+	if l.value == 0 {
+		return
+	}
+
+	l.parent.Incr(l.value)
+	l.value = 0
+}
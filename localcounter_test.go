@@ -0,0 +1,65 @@
+package ratecounter
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLocalCounter(t *testing.T) {
+	r := NewRateCounter(1 * time.Second)
+
+	func() {
+		local := r.Local()
+		defer local.Flush()
+
+		local.Incr(1)
+		local.Incr(2)
+		if val := local.Value(); val != 3 {
+			t.Error("Expected ", val, " to equal ", 3)
+		}
+		// Nothing reaches the parent until the flush
+		if val := r.Rate(); val != 0 {
+			t.Error("Expected ", val, " to equal ", 0)
+		}
+	}()
+
+	if val := r.Rate(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+}
+
+func TestLocalCounter_Concurrent(t *testing.T) {
+	r := NewRateCounter(1 * time.Second)
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local := r.Local()
+			for j := 0; j < 100; j++ {
+				local.Incr(1)
+			}
+			local.Flush()
+			local.Flush()
+		}()
+	}
+	wg.Wait()
+
+	if val := r.Rate(); val != 1000 {
+		t.Error("Expected ", val, " to equal ", 1000)
+	}
+}
+
+func BenchmarkLocalCounter(b *testing.B) {
+	r := NewRateCounter(1 * time.Second)
+
+	for i := 0; i < b.N; i++ {
+		local := r.Local()
+		for j := 0; j < 10; j++ {
+			local.Incr(1)
+		}
+		local.Flush()
+	}
+}
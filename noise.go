@@ -0,0 +1,61 @@
+package ratecounter
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+)
+
+// A Noise configures how rates are obscured before being shown to someone
+// who must not learn exact counts, e.g. usage rates exposed to tenants in a
+// shared system. Counters keep exact values; apply Noise only on export.
+type Noise struct {
+	// Epsilon is the differential privacy budget for each released value.
+	// Laplace noise with scale Sensitivity/Epsilon is added. Zero disables
+	// noise.
+	Epsilon float64
+	// Sensitivity is the most one individual can change a rate by, 1 if zero
+	Sensitivity float64
+	// Round rounds the result to a multiple of Round, if positive
+	Round int64
+}
+
+// Apply returns rate with noise added and rounding applied. Results are
+// never negative for a non-negative rate.
+func (n Noise) Apply(rate int64) int64 {
+	value := float64(rate)
+
+	if n.Epsilon > 0 {
+		sensitivity := n.Sensitivity
+		if sensitivity == 0 {
+			sensitivity = 1
+		}
+		value += laplace(sensitivity / n.Epsilon)
+	}
+
+	if n.Round > 0 {
+		value = math.Round(value/float64(n.Round)) * float64(n.Round)
+	}
+
+	if rate >= 0 && value < 0 {
+		value = 0
+	}
+
+	return int64(math.Round(value))
+}
+
+// laplace draws from a Laplace distribution centred on zero, using
+// cryptographically secure randomness so the noise cannot be predicted
+func laplace(scale float64) float64 {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("ratecounter: reading random bytes: " + err.Error())
+	}
+
+	// Uniform in (-0.5, 0.5)
+	u := (float64(binary.LittleEndian.Uint64(b[:])>>11)+0.5)/(1<<53) - 0.5
+	if u < 0 {
+		return scale * math.Log(1+2*u)
+	}
+	return -scale * math.Log(1-2*u)
+}
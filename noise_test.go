@@ -0,0 +1,60 @@
+package ratecounter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNoise_Disabled(t *testing.T) {
+	if val := (Noise{}).Apply(42); val != 42 {
+		t.Error("Expected ", val, " to equal ", 42)
+	}
+}
+
+func TestNoise_Round(t *testing.T) {
+	n := Noise{Round: 10}
+
+	for rate, expected := range map[int64]int64{42: 40, 45: 50, 3: 0, 0: 0} {
+		if val := n.Apply(rate); val != expected {
+			t.Error("Expected ", val, " to equal ", expected)
+		}
+	}
+}
+
+func TestNoise_Laplace(t *testing.T) {
+	n := Noise{Epsilon: 0.5}
+
+	const samples = 20000
+	var sum, absSum float64
+	differ := false
+	for i := 0; i < samples; i++ {
+		val := n.Apply(1000)
+		if val != 1000 {
+			differ = true
+		}
+		sum += float64(val - 1000)
+		absSum += math.Abs(float64(val - 1000))
+	}
+
+	if !differ {
+		t.Fatal("Expected noise to change the value")
+	}
+	// The mean is zero and the mean absolute deviation is the scale,
+	// 1/epsilon = 2
+	if mean := sum / samples; math.Abs(mean) > 0.2 {
+		t.Error("Expected mean noise ", mean, " to be close to ", 0)
+	}
+	if mad := absSum / samples; math.Abs(mad-2) > 0.3 {
+		t.Error("Expected mean absolute noise ", mad, " to be close to ", 2)
+	}
+}
+
+func TestNoise_NeverNegative(t *testing.T) {
+	n := Noise{Epsilon: 0.1}
+
+	for i := 0; i < 1000; i++ {
+		if val := n.Apply(0); val < 0 {
+			t.Fatal("Expected ", val, " to not be negative")
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package ratecounter
+
+// A NopRateCounter is a Rater which discards every event and always reports
+// a rate of zero. It lets instrumentation stay in place while disabled by
+// configuration, without nil checks at every call site.
+type NopRateCounter struct{}
+
+// Incr discards the event
+func (NopRateCounter) Incr(val int64) {}
+
+// IncrAndRate discards the event and returns zero
+func (NopRateCounter) IncrAndRate(val int64) int64 { return 0 }
+
+// Rate always returns zero
+func (NopRateCounter) Rate() int64 { return 0 }
+
+func (NopRateCounter) String() string { return "0" }
+
+var _ Rater = NopRateCounter{}
@@ -0,0 +1,25 @@
+package ratecounter
+
+import "testing"
+
+func TestNopRateCounter(t *testing.T) {
+	var r Rater = NopRateCounter{}
+
+	r.Incr(5)
+	if val := r.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+
+	if s := (NopRateCounter{}).String(); s != "0" {
+		t.Error("Expected ", s, " to equal ", "0")
+	}
+}
+
+func BenchmarkNopRateCounter(b *testing.B) {
+	var r Rater = NopRateCounter{}
+
+	for i := 0; i < b.N; i++ {
+		r.Incr(1)
+		r.Rate()
+	}
+}
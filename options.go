@@ -0,0 +1,201 @@
+package ratecounter
+
+import (
+	"context"
+	"time"
+)
+
+// An Option configures a RateCounter at construction time
+type Option func(*RateCounter)
+
+// WithResolution sets the number of partials the interval is split into,
+// default is 20
+func WithResolution(resolution int) Option {
+	if resolution < 1 {
+		panic("RateCounter resolution cannot be less than 1")
+	}
+
+	return func(r *RateCounter) {
+		r.partials = make([]partial, resolution)
+	}
+}
+
+// WithExactWindow splits the interval into one partial per millisecond, so
+// Rate counts the events in exactly the last interval, to the precision of
+// the clock, rather than dropping a whole partial at a time. It costs a
+// partial per millisecond of the interval in memory, and Rate sums them
+// all, so it suits short intervals; a one second interval takes about
+// 24KB. Like WithResolution, whichever of the two comes last wins.
+func WithExactWindow() Option {
+	return func(r *RateCounter) {
+		resolution := int(r.interval)
+		if resolution < 1 {
+			resolution = 1
+		}
+		r.partials = make([]partial, resolution)
+	}
+}
+
+// WithAlignedWindow starts every partial on a wall-clock boundary, counted
+// from the Unix epoch, rather than relative to when the counter was created.
+// With a resolution of 1 the counter reports the events in the current
+// calendar interval, e.g. "requests this minute" for a one minute interval.
+// Boundaries are in UTC; the interval should be a whole number of
+// milliseconds per partial.
+func WithAlignedWindow() Option {
+	return func(r *RateCounter) {
+		r.aligned = true
+	}
+}
+
+// WithPhase offsets when the counter's partials turn over by offset, so
+// many counters with the same interval rotate at staggered times rather
+// than all at once. With WithAlignedWindow the boundaries move from the
+// epoch to offset past it; otherwise the first partial is shortened by
+// offset. Only the offset modulo the partial length matters.
+func WithPhase(offset time.Duration) Option {
+	if offset < 0 {
+		panic("RateCounter phase cannot be negative")
+	}
+
+	return func(r *RateCounter) {
+		r.phase = uint32(offset.Nanoseconds() / 1000000)
+	}
+}
+
+// WithLateness sets how far in the past an event passed to IncrAt may be
+// and still be counted. Later events are dropped and reported by
+// DroppedLate. The default is to accept anything within the interval.
+func WithLateness(lateness time.Duration) Option {
+	return func(r *RateCounter) {
+		r.lateness = uint32(lateness.Nanoseconds() / 1000000)
+	}
+}
+
+// WithUnit sets what the counter counts, e.g. "requests" or "bytes". It is
+// carried through snapshots and exporters.
+func WithUnit(unit string) Option {
+	return func(r *RateCounter) {
+		r.unit = unit
+	}
+}
+
+// WithDescription sets a human readable description of the counter. It is
+// carried through snapshots and exporters.
+func WithDescription(description string) Option {
+	return func(r *RateCounter) {
+		r.description = description
+	}
+}
+
+// WithTags sets dimensional tags on the counter, e.g. {"route": "/users"},
+// which are carried through snapshots and exporters. The map is copied.
+func WithTags(tags map[string]string) Option {
+	return func(r *RateCounter) {
+		r.tags = make(map[string]string, len(tags))
+		for k, v := range tags {
+			r.tags[k] = v
+		}
+	}
+}
+
+// WithSmoothing enables SmoothedRate, an exponentially weighted moving
+// average of the rate sampled each time a partial is dropped. alpha, between
+// 0 and 1, is the weight of the newest sample: smaller values give calmer
+// lines. Rate is unaffected, so alerting can keep using raw values.
+func WithSmoothing(alpha float64) Option {
+	if alpha <= 0 || alpha > 1 {
+		panic("RateCounter smoothing must be greater than 0 and at most 1")
+	}
+
+	return func(r *RateCounter) {
+		r.smoothing = alpha
+	}
+}
+
+// WithSampleRate declares that only a fraction of events, between 0 and 1,
+// are passed to the counter, e.g. 0.01 when 1% of traffic is instrumented.
+// Rate then scales the recorded count back up to the full population.
+func WithSampleRate(fraction float64) Option {
+	if fraction <= 0 || fraction > 1 {
+		panic("RateCounter sample rate must be greater than 0 and at most 1")
+	}
+
+	return func(r *RateCounter) {
+		r.sampleRate = fraction
+	}
+}
+
+// WithClampAtZero reports a rate of zero whenever the events in the window
+// sum to less than zero, e.g. when a gauge-style counter sees decrements
+// for increments which have already left the window. Without it negative
+// rates are reported as they are. Buckets and OnRotate values stay raw.
+func WithClampAtZero() Option {
+	return func(r *RateCounter) {
+		r.clamp = true
+	}
+}
+
+// WithPreviousWindow keeps the interval before the current one as it
+// slides out of the window, for PreviousRate and ChangePercent
+func WithPreviousWindow() Option {
+	return func(r *RateCounter) {
+		r.keepPrevious = true
+	}
+}
+
+// WithTicker rotates the partials from a background goroutine, as if Start
+// was called once the counter is built. Call Stop when done with the
+// counter to end the goroutine.
+func WithTicker() Option {
+	return func(r *RateCounter) {
+		r.background = true
+	}
+}
+
+// WithShards backs each partial with a ShardedCounter of n shards, so many
+// goroutines on different CPUs incrementing the counter at once don't all
+// contend for one word. It trades memory, a cache line per shard per
+// partial, and slower reads for faster concurrent writes; a few times
+// GOMAXPROCS shards is a good choice.
+func WithShards(n int) Option {
+	if n < 1 {
+		panic("RateCounter shards cannot be less than 1")
+	}
+
+	return func(r *RateCounter) {
+		r.shards = n
+	}
+}
+
+// OnRotate calls fn each time a partial leaves the window, with the value
+// it held, as recorded before any sample rate scaling, and when it left, so
+// each partial's count can be flushed elsewhere, e.g. to a log or StatsD.
+// Calls are made one at a time and in order, on whichever goroutine moves
+// the counter on, while others moving it on wait, so fn should be quick;
+// without WithTicker it runs late, on the next use of the counter. After
+// an idle spell longer than the interval only the partials which were in
+// the window are reported.
+func OnRotate(fn func(droppedValue int64, at time.Time)) Option {
+	return func(r *RateCounter) {
+		r.onRotate = fn
+	}
+}
+
+// WithContext closes the counter once ctx is done, with or without a
+// ticker: the ticker started by WithTicker or Start ends, later calls to
+// Start do nothing, and the counter's watches are removed, so tests and
+// short-lived programs need not Close every counter. The counter itself
+// keeps working, rotated as it is used. Passed to NewRegistry it binds
+// every counter in the registry.
+func WithContext(ctx context.Context) Option {
+	return func(r *RateCounter) {
+		r.ctx = ctx
+	}
+}
+
+// NewRateCounterWithOptions Constructs a new RateCounter, applying opts. It
+// is the same as NewRateCounter, which now takes options itself.
+func NewRateCounterWithOptions(intrvl time.Duration, opts ...Option) *RateCounter {
+	return NewRateCounter(intrvl, opts...)
+}
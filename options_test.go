@@ -0,0 +1,553 @@
+package ratecounter
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewRateCounterWithOptions(t *testing.T) {
+	r := NewRateCounterWithOptions(1*time.Second, WithResolution(60))
+
+	if len(r.partials) != 60 {
+		t.Error("Expected ", len(r.partials), " partials to equal ", 60)
+	}
+
+	r.Incr(2)
+	if val := r.Rate(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+}
+
+func TestNewRateCounterWithOptions_Defaults(t *testing.T) {
+	r := NewRateCounterWithOptions(1 * time.Second)
+
+	if len(r.partials) != 20 {
+		t.Error("Expected ", len(r.partials), " partials to equal ", 20)
+	}
+}
+
+func TestWithExactWindow(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounter(100*time.Millisecond, WithExactWindow(), WithClock(clock))
+
+	if len(r.partials) != 100 {
+		t.Error("Expected ", len(r.partials), " partials to equal ", 100)
+	}
+
+	r.Incr(1)
+	clock.Add(60 * time.Millisecond)
+	r.Incr(2)
+
+	// Each event leaves exactly one interval after it arrived
+	clock.Add(39 * time.Millisecond)
+	if val := r.Rate(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+	clock.Add(1 * time.Millisecond)
+	if val := r.Rate(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+	clock.Add(60 * time.Millisecond)
+	if val := r.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestWithClampAtZero(t *testing.T) {
+	clock := newManualClock()
+	clamped := NewRateCounter(200*time.Millisecond, WithResolution(4), WithClock(clock), WithClampAtZero())
+	raw := NewRateCounter(200*time.Millisecond, WithResolution(4), WithClock(clock))
+
+	// A gauge of connections opened minus closed, whose opens have left
+	// the window before the closes
+	for _, r := range []*RateCounter{clamped, raw} {
+		r.Incr(3)
+	}
+	clock.Add(150 * time.Millisecond)
+	for _, r := range []*RateCounter{clamped, raw} {
+		r.Incr(-4)
+	}
+
+	if val := clamped.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+	if val := clamped.IncrAndRate(1); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+
+	clock.Add(100 * time.Millisecond)
+	if val := raw.Rate(); val != -4 {
+		t.Error("Expected ", val, " to equal ", -4)
+	}
+	if val := clamped.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+	if val := clamped.Snapshot().Rate; val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+
+	// Positive rates are unchanged
+	clamped.Incr(10)
+	if val := clamped.Rate(); val != 7 {
+		t.Error("Expected ", val, " to equal ", 7)
+	}
+}
+
+func TestWithResolution_Min(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Resolution < 1 did not panic")
+		}
+	}()
+
+	WithResolution(0)
+}
+
+func TestWithAlignedWindow(t *testing.T) {
+	interval := 200 * time.Millisecond
+	r := NewRateCounterWithOptions(interval, WithResolution(1), WithAlignedWindow())
+
+	if start := r.periodStart(r.period(r.now())); start%200 != 0 {
+		t.Error("Expected partial start ", start, " to be aligned to ", 200)
+	}
+
+	// Wait for the start of the next window
+	now := UnixMilli()
+	time.Sleep(time.Duration(200-now%200+10) * time.Millisecond)
+
+	r.Incr(1)
+	if val := r.Rate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if val := r.Rate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+
+	// The window ends at the next boundary, not an interval after the event
+	now = UnixMilli()
+	time.Sleep(time.Duration(200-now%200+10) * time.Millisecond)
+	if val := r.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+	if start := r.periodStart(r.period(r.now())); start%200 != 0 {
+		t.Error("Expected partial start ", start, " to be aligned to ", 200)
+	}
+}
+
+func TestWithLateness(t *testing.T) {
+	r := NewRateCounterWithOptions(1*time.Second, WithLateness(100*time.Millisecond))
+
+	r.IncrAt(time.Now().Add(-50*time.Millisecond), 1)
+	r.IncrAt(time.Now().Add(-500*time.Millisecond), 2) // Within the window, but too late
+	r.IncrAt(time.Now().Add(-5*time.Second), 4)        // Outside the window
+
+	if val := r.Rate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+	if val := r.DroppedLate(); val != 6 {
+		t.Error("Expected dropped ", val, " to equal ", 6)
+	}
+}
+
+func TestWithUnitAndDescription(t *testing.T) {
+	r := NewRateCounterWithOptions(1*time.Second, WithUnit("bytes"), WithDescription("Bytes received"))
+
+	if r.Unit() != "bytes" {
+		t.Error("Expected ", r.Unit(), " to equal ", "bytes")
+	}
+	if r.Description() != "Bytes received" {
+		t.Error("Expected ", r.Description(), " to equal ", "Bytes received")
+	}
+
+	s := r.Snapshot()
+	if s.Unit != "bytes" || s.Description != "Bytes received" {
+		t.Error("Expected snapshot to carry metadata, got", s.Unit, s.Description)
+	}
+}
+
+func TestWithSampleRate(t *testing.T) {
+	r := NewRateCounterWithOptions(1*time.Second, WithSampleRate(0.01))
+
+	r.Incr(3)
+	if val := r.Rate(); val != 300 {
+		t.Error("Expected ", val, " to equal ", 300)
+	}
+	if val := r.IncrAndRate(1); val != 400 {
+		t.Error("Expected ", val, " to equal ", 400)
+	}
+	if s := r.Snapshot(); s.Rate != 400 {
+		t.Error("Expected snapshot rate ", s.Rate, " to equal ", 400)
+	}
+}
+
+func TestWithSampleRate_Invalid(t *testing.T) {
+	for _, fraction := range []float64{0, -1, 1.5} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Sample rate %v did not panic", fraction)
+				}
+			}()
+
+			WithSampleRate(fraction)
+		}()
+	}
+}
+
+func TestWithPreviousWindow(t *testing.T) {
+	interval := 200 * time.Millisecond
+	r := NewRateCounterWithOptions(interval, WithResolution(4), WithPreviousWindow())
+
+	check := func(expectedRate, expectedPrevious int64) {
+		rate, previous := r.Rate(), r.PreviousRate()
+		if rate != expectedRate {
+			t.Error("Expected rate ", rate, " to equal ", expectedRate)
+		}
+		if previous != expectedPrevious {
+			t.Error("Expected previous ", previous, " to equal ", expectedPrevious)
+		}
+	}
+
+	r.Incr(10)
+	check(10, 0)
+	if change := r.ChangePercent(); change != 0 {
+		t.Error("Expected ", change, " to equal ", 0)
+	}
+
+	time.Sleep(interval + 60*time.Millisecond)
+	r.Incr(6)
+	check(6, 10)
+	if change := r.ChangePercent(); change != -40 {
+		t.Error("Expected ", change, " to equal ", -40)
+	}
+
+	time.Sleep(interval + 60*time.Millisecond)
+	check(0, 6)
+
+	// Idle for longer than both intervals
+	time.Sleep(3 * interval)
+	check(0, 0)
+}
+
+func TestWithPreviousWindow_Disabled(t *testing.T) {
+	interval := 100 * time.Millisecond
+	r := NewRateCounter(interval)
+
+	r.Incr(1)
+	time.Sleep(interval + 20*time.Millisecond)
+	if val := r.PreviousRate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestWithTags(t *testing.T) {
+	tags := map[string]string{"route": "/users"}
+	r := NewRateCounterWithOptions(1*time.Second, WithTags(tags))
+	tags["route"] = "/changed"
+
+	if val := r.Tags()["route"]; val != "/users" {
+		t.Error("Expected ", val, " to equal ", "/users")
+	}
+
+	r.Tags()["route"] = "/changed"
+	if val := r.Snapshot().Tags["route"]; val != "/users" {
+		t.Error("Expected ", val, " to equal ", "/users")
+	}
+
+	if tags := NewRateCounter(1 * time.Second).Tags(); tags != nil {
+		t.Error("Expected ", tags, " to be nil")
+	}
+}
+
+func TestWithSmoothing(t *testing.T) {
+	interval := 200 * time.Millisecond
+	r := NewRateCounterWithOptions(interval, WithResolution(4), WithSmoothing(0.5))
+
+	r.Incr(8)
+	if val := r.SmoothedRate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+
+	// One rotation: the smoothed rate moves halfway to the raw rate
+	time.Sleep(60 * time.Millisecond)
+	if val := r.RawRate(); val != 8 {
+		t.Error("Expected raw ", val, " to equal ", 8)
+	}
+	if val := r.SmoothedRate(); val != 4 {
+		t.Error("Expected smoothed ", val, " to equal ", 4)
+	}
+
+	// Once the events expire the raw rate drops at once, the smoothed rate
+	// decays
+	time.Sleep(interval)
+	if val := r.RawRate(); val != 0 {
+		t.Error("Expected raw ", val, " to equal ", 0)
+	}
+	if val := r.SmoothedRate(); val <= 0 || val >= 8 {
+		t.Error("Expected smoothed ", val, " to be between ", 0, " and ", 8)
+	}
+}
+
+func TestWithSmoothing_Disabled(t *testing.T) {
+	r := NewRateCounter(1 * time.Second)
+
+	r.Incr(3)
+	if val := r.SmoothedRate(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+}
+
+func TestNewRateCounter_Options(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounter(1*time.Second, WithResolution(5), WithClock(clock), WithUnit("requests"))
+
+	if len(r.partials) != 5 {
+		t.Error("Expected ", len(r.partials), " to equal ", 5)
+	}
+	if r.Unit() != "requests" {
+		t.Error("Expected ", r.Unit(), " to equal ", "requests")
+	}
+
+	r.Incr(1)
+	clock.Add(2 * time.Second)
+	if val := r.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestWithTicker(t *testing.T) {
+	r := NewRateCounter(100*time.Millisecond, WithResolution(4), WithTicker())
+	defer r.Stop()
+
+	if r.ticker == nil {
+		t.Fatal("Expected WithTicker to start the ticker")
+	}
+
+	r.Incr(5)
+	before := atomic.LoadUint64(&r.epoch)
+	time.Sleep(250 * time.Millisecond)
+	if after := atomic.LoadUint64(&r.epoch); after < before+4 {
+		t.Error("Expected the counter to move on from ", before, " without reads, got ", after)
+	}
+}
+
+func TestWithPhase_Aligned(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounter(1*time.Second, WithResolution(1), WithAlignedWindow(), WithPhase(250*time.Millisecond), WithClock(clock))
+
+	// Windows run from a quarter past each second
+	if start := r.periodStart(r.period(r.now())); start%1000 != 250 {
+		t.Error("Expected partial start ", start, " to be offset by ", 250)
+	}
+
+	r.Incr(1)
+	clock.Add(240 * time.Millisecond)
+	if val := r.Rate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+	clock.Add(20 * time.Millisecond)
+	if val := r.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+	if start := r.periodStart(r.period(r.now())); start%1000 != 250 {
+		t.Error("Expected partial start ", start, " to be offset by ", 250)
+	}
+}
+
+func TestWithPhase_Relative(t *testing.T) {
+	clock := newManualClock()
+	plain := NewRateCounter(400*time.Millisecond, WithResolution(4), WithClock(clock))
+	shifted := NewRateCounter(400*time.Millisecond, WithResolution(4), WithPhase(60*time.Millisecond), WithClock(clock))
+
+	plain.Incr(1)
+	shifted.Incr(1)
+	clock.Add(45 * time.Millisecond)
+
+	// Only the shifted counter has moved on to its next partial
+	if b := plain.Buckets(); b[3] != 1 {
+		t.Error("Expected ", b, " to end with ", 1)
+	}
+	if b := shifted.Buckets(); b[3] != 0 || b[2] != 1 {
+		t.Error("Expected ", b, " to end with ", []int64{1, 0})
+	}
+}
+
+func TestOnRotate(t *testing.T) {
+	clock := newManualClock()
+	type drop struct {
+		value int64
+		at    time.Time
+	}
+	var drops []drop
+	r := NewRateCounter(400*time.Millisecond, WithResolution(4), WithClock(clock),
+		OnRotate(func(value int64, at time.Time) {
+			drops = append(drops, drop{value, at})
+		}))
+
+	start := clock.Now()
+	r.Incr(3)
+	clock.Add(100 * time.Millisecond)
+	r.Incr(5)
+
+	// Nothing has left the window yet
+	clock.Add(250 * time.Millisecond)
+	if r.Rate(); len(drops) != 0 {
+		t.Error("Expected no partials to have left the window, got ", drops)
+	}
+
+	clock.Add(100 * time.Millisecond)
+	if val := r.Rate(); val != 5 {
+		t.Error("Expected ", val, " to equal ", 5)
+	}
+	if len(drops) != 1 || drops[0].value != 3 || !drops[0].at.Equal(start.Add(400*time.Millisecond)) {
+		t.Fatal("Expected the first partial to be dropped with ", 3, ", got ", drops)
+	}
+
+	// Partials which leave while nobody is looking are still reported, in
+	// order
+	clock.Add(1 * time.Second)
+	r.Rate()
+	if len(drops) != 5 || drops[1].value != 5 {
+		t.Fatal("Expected the second partial to be dropped with ", 5, ", got ", drops)
+	}
+	for ii := 2; ii < len(drops); ii++ {
+		if drops[ii].value != 0 || !drops[ii].at.After(drops[ii-1].at) {
+			t.Error("Expected drop ", ii, " to be empty and in order, got ", drops[ii])
+		}
+	}
+
+	// The partials being kept does not turn on PreviousRate
+	if val := r.PreviousRate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestWithSmoothing_ConcurrentRotation(t *testing.T) {
+	// A clock which moves on a millisecond, one partial, on every read, so
+	// concurrent readers keep moving the counter on at once
+	base := uint64(1000000)
+	var ticks uint64
+	shared := NewRateCounter(4*time.Millisecond, WithResolution(4), WithSmoothing(0.001),
+		func(r *RateCounter) {
+			r.clock = func() uint64 { return base + atomic.AddUint64(&ticks, 1) - 1 }
+		})
+	shared.Incr(64)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(8)
+	for i := 0; i < 8; i++ {
+		go func() {
+			for j := 0; j < 1000; j++ {
+				shared.Rate()
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	shared.Rate()
+
+	// Each period's update must have been done exactly once and in order,
+	// as a single reader stepping through the same periods does
+	now := base
+	serial := NewRateCounter(4*time.Millisecond, WithResolution(4), WithSmoothing(0.001),
+		func(r *RateCounter) {
+			r.clock = func() uint64 { return now }
+		})
+	now++
+	serial.Incr(64)
+	for now++; now < base+atomic.LoadUint64(&ticks); now++ {
+		serial.Rate()
+	}
+
+	a := math.Float64frombits(atomic.LoadUint64(&shared.smoothed))
+	b := math.Float64frombits(atomic.LoadUint64(&serial.smoothed))
+	if math.Abs(a-b) > 1e-9*b {
+		t.Error("Expected ", a, " to equal ", b)
+	}
+}
+
+func TestOnRotate_Concurrent(t *testing.T) {
+	// A clock which moves on a partial on every read, so concurrent readers
+	// keep moving the counter on at once
+	var ticks, inflight, overlaps int64
+	var last time.Time
+	var calls int
+	r := NewRateCounter(4*time.Millisecond, WithResolution(4),
+		func(r *RateCounter) {
+			r.clock = func() uint64 { return 1000000 + uint64(atomic.AddInt64(&ticks, 1)) }
+		},
+		OnRotate(func(value int64, at time.Time) {
+			if atomic.AddInt64(&inflight, 1) > 1 {
+				atomic.AddInt64(&overlaps, 1)
+			}
+			if !at.After(last) {
+				t.Error("Expected ", at, " to be after ", last)
+			}
+			last = at
+			calls++
+			atomic.AddInt64(&inflight, -1)
+		}))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(8)
+	for i := 0; i < 8; i++ {
+		go func() {
+			for j := 0; j < 500; j++ {
+				r.Incr(1)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	if overlaps != 0 {
+		t.Error("Expected no overlapping calls, got ", overlaps)
+	}
+	if calls == 0 {
+		t.Error("Expected some partials to leave the window")
+	}
+}
+
+func TestWithShards(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounter(400*time.Millisecond, WithResolution(4), WithShards(8), WithClock(clock))
+
+	if r.partials[0].shards == nil || len(r.partials[0].shards.shards) != 8 {
+		t.Fatal("Expected each partial to be sharded")
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			for j := 0; j < 100; j++ {
+				r.Incr(1)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	if val := r.Rate(); val != 1000 {
+		t.Error("Expected ", val, " to equal ", 1000)
+	}
+
+	// Partials are still cleared as they are reused
+	clock.Add(200 * time.Millisecond)
+	r.Incr(5)
+	clock.Add(300 * time.Millisecond)
+	if val := r.Rate(); val != 5 {
+		t.Error("Expected ", val, " to equal ", 5)
+	}
+	if b := r.Buckets(); b[0] != 5 {
+		t.Error("Expected ", b, " to hold ", 5)
+	}
+
+	r.Reset()
+	if val := r.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
@@ -0,0 +1,46 @@
+package ratecounter
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// An OverflowPolicy decides what IncrChecked does when an increment would
+// take a Counter past the range of an int64
+type OverflowPolicy int
+
+const (
+	// OverflowWrap wraps around, as Incr does
+	OverflowWrap OverflowPolicy = iota
+	// OverflowSaturate stops at math.MaxInt64 or math.MinInt64
+	OverflowSaturate
+	// OverflowReject leaves the counter unchanged
+	OverflowReject
+)
+
+// IncrChecked increments the counter by val, applying policy if that would
+// overflow, and reports whether it did. Callers can log, alert or return an
+// error when it reports true.
+func (c *Counter) IncrChecked(val int64, policy OverflowPolicy) bool {
+	for {
+		old := c.Value()
+		next := old + val
+		overflowed := (val > 0 && next < old) || (val < 0 && next > old)
+
+		if overflowed {
+			switch policy {
+			case OverflowSaturate:
+				next = math.MaxInt64
+				if val < 0 {
+					next = math.MinInt64
+				}
+			case OverflowReject:
+				return true
+			}
+		}
+
+		if atomic.CompareAndSwapInt64((*int64)(c), old, next) {
+			return overflowed
+		}
+	}
+}
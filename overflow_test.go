@@ -0,0 +1,34 @@
+package ratecounter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCounter_IncrChecked(t *testing.T) {
+	check := func(policy OverflowPolicy, start, val, expected int64, expectedOverflow bool) {
+		c := Counter(start)
+		overflowed := c.IncrChecked(val, policy)
+		if overflowed != expectedOverflow {
+			t.Error("Expected overflow ", overflowed, " to equal ", expectedOverflow)
+		}
+		if c.Value() != expected {
+			t.Error("Expected ", c.Value(), " to equal ", expected)
+		}
+	}
+
+	// No overflow behaves like Incr under every policy
+	for _, policy := range []OverflowPolicy{OverflowWrap, OverflowSaturate, OverflowReject} {
+		check(policy, 1, 2, 3, false)
+		check(policy, 1, -2, -1, false)
+	}
+
+	check(OverflowWrap, math.MaxInt64, 1, math.MinInt64, true)
+	check(OverflowWrap, math.MinInt64, -1, math.MaxInt64, true)
+
+	check(OverflowSaturate, math.MaxInt64-1, 5, math.MaxInt64, true)
+	check(OverflowSaturate, math.MinInt64+1, -5, math.MinInt64, true)
+
+	check(OverflowReject, math.MaxInt64-1, 5, math.MaxInt64-1, true)
+	check(OverflowReject, math.MinInt64+1, -5, math.MinInt64+1, true)
+}
@@ -0,0 +1,312 @@
+package ratecounter
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A PercentileCounter is a thread-safe histogram of the values observed in
+// the last interval, for percentiles such as p99 latency rather than rates.
+// Like a RateCounter it splits the interval into partials, each holding a
+// fixed-bucket histogram, and drops the oldest as time passes.
+type PercentileCounter struct {
+	// Upper bounds of each bucket; a final bucket holds everything above
+	bounds []float64
+	// partials[i] holds a count for each bucket
+	partials [][]Counter
+	// sums[i] holds the bits of the float64 sum of partial i's values
+	sums []uint64
+	// The partial period each partial was last reset for
+	epochs   []uint64
+	interval uint32
+	// Where the time comes from, in milliseconds, UnixMilli if nil
+	clock func() uint64
+	sync.Mutex
+}
+
+// NewPercentileCounter Constructs a new PercentileCounter for the interval
+// provided, with buckets whose upper bounds are the increasing values given.
+// Of the options, only WithResolution and WithClock apply; the rest are
+// ignored.
+func NewPercentileCounter(intrvl time.Duration, bounds []float64, opts ...Option) *PercentileCounter {
+	if len(bounds) == 0 {
+		panic("PercentileCounter needs at least one bucket bound")
+	}
+	if !sort.Float64sAreSorted(bounds) {
+		panic("PercentileCounter bucket bounds must be increasing")
+	}
+
+	// Read the options from a counter which is never used
+	probe := &RateCounter{partials: make([]partial, 20)}
+	for _, opt := range opts {
+		opt(probe)
+	}
+
+	p := &PercentileCounter{
+		bounds:   append([]float64(nil), bounds...),
+		interval: uint32(intrvl.Nanoseconds() / 1000000),
+		clock:    probe.clock,
+	}
+	p.resize(len(probe.partials))
+	return p
+}
+
+// ExponentialBounds returns count bucket bounds starting at start, each
+// factor times the last, e.g. ExponentialBounds(1, 2, 10) for 1ms to 512ms
+func ExponentialBounds(start, factor float64, count int) []float64 {
+	if start <= 0 || factor <= 1 || count < 1 {
+		panic("ExponentialBounds needs a positive start, factor above 1 and count of at least 1")
+	}
+
+	bounds := make([]float64, count)
+	for ii := range bounds {
+		bounds[ii] = start
+		start *= factor
+	}
+	return bounds
+}
+
+func (p *PercentileCounter) resize(resolution int) {
+	p.partials = make([][]Counter, resolution)
+	for ii := range p.partials {
+		p.partials[ii] = make([]Counter, len(p.bounds)+1)
+	}
+	p.sums = make([]uint64, resolution)
+	p.epochs = make([]uint64, resolution)
+}
+
+func (p *PercentileCounter) now() uint64 {
+	if p.clock != nil {
+		return p.clock()
+	}
+	return UnixMilli()
+}
+
+// epoch returns which partial period now falls in
+func (p *PercentileCounter) epoch(now uint64) uint64 {
+	millis := uint64(p.interval) / uint64(len(p.partials))
+	if millis == 0 {
+		millis = 1
+	}
+	return now / millis
+}
+
+// Observe Add a value into the PercentileCounter. A value whose partial has
+// already been reused for a later period, because the goroutine observing
+// it was delayed by a whole interval, has left the window and is dropped.
+func (p *PercentileCounter) Observe(val float64) {
+	epoch := p.epoch(p.now())
+	ii := int(epoch % uint64(len(p.partials)))
+
+	if stored := atomic.LoadUint64(&p.epochs[ii]); stored > epoch {
+		return
+	} else if stored < epoch {
+		// The partial is left over from an earlier period, so clear it
+		p.Lock()
+		stored = p.epochs[ii]
+		if stored < epoch {
+			for jj := range p.partials[ii] {
+				p.partials[ii][jj].Reset()
+			}
+			atomic.StoreUint64(&p.sums[ii], 0)
+			atomic.StoreUint64(&p.epochs[ii], epoch)
+		}
+		p.Unlock()
+		if stored > epoch {
+			return
+		}
+	}
+
+	p.partials[ii][sort.SearchFloat64s(p.bounds, val)].Incr(1)
+	for {
+		old := atomic.LoadUint64(&p.sums[ii])
+		sum := math.Float64bits(math.Float64frombits(old) + val)
+		if atomic.CompareAndSwapUint64(&p.sums[ii], old, sum) {
+			return
+		}
+	}
+}
+
+// buckets returns the total count in each bucket over the last interval
+func (p *PercentileCounter) buckets() []int64 {
+	totals, _ := p.totals()
+	return totals
+}
+
+// totals returns the total count in each bucket, and the sum of the values,
+// over the last interval
+func (p *PercentileCounter) totals() ([]int64, float64) {
+	epoch := p.epoch(p.now())
+	resolution := uint64(len(p.partials))
+	totals := make([]int64, len(p.bounds)+1)
+	var sum float64
+
+	for ii := range p.partials {
+		if e := atomic.LoadUint64(&p.epochs[ii]); e+resolution <= epoch || e > epoch {
+			// Expired, or never used
+			continue
+		}
+		for jj := range p.partials[ii] {
+			totals[jj] += p.partials[ii][jj].Value()
+		}
+		sum += math.Float64frombits(atomic.LoadUint64(&p.sums[ii]))
+	}
+
+	return totals, sum
+}
+
+// Count Return the number of values observed in the last interval
+func (p *PercentileCounter) Count() int64 {
+	var count int64
+	for _, c := range p.buckets() {
+		count += c
+	}
+	return count
+}
+
+// Sum Return the sum of the values observed in the last interval
+func (p *PercentileCounter) Sum() float64 {
+	_, sum := p.totals()
+	return sum
+}
+
+// Percentile Return an estimate of the q-th quantile, between 0 and 1, of
+// the values observed in the last interval, interpolating linearly within
+// the bucket it falls in. Values above the last bound are reported as the
+// last bound. It returns 0 when nothing has been observed.
+func (p *PercentileCounter) Percentile(q float64) float64 {
+	return percentile(p.bounds, p.buckets(), q)
+}
+
+// A PercentileSnapshot is a point-in-time copy of a PercentileCounter's
+// histogram. It carries the bucket bounds, so a consumer can interpret the
+// counts, or notice that the bounds changed, without being told them
+// separately.
+type PercentileSnapshot struct {
+	// Interval is the window the counter covers
+	Interval time.Duration
+	// Bounds holds the upper bound of each bucket. It is shared with the
+	// counter and must not be modified.
+	Bounds []float64
+	// Counts holds the number of values in each bucket over the interval.
+	// It has one more entry than Bounds, counting the values above the
+	// last bound.
+	Counts []int64
+	// Sum is the sum of the values over the interval
+	Sum float64
+}
+
+// Snapshot returns a copy of the counter's histogram over the last interval
+func (p *PercentileCounter) Snapshot() PercentileSnapshot {
+	counts, sum := p.totals()
+	return PercentileSnapshot{
+		Interval: time.Duration(p.interval) * time.Millisecond,
+		Bounds:   p.bounds,
+		Counts:   counts,
+		Sum:      sum,
+	}
+}
+
+// Count returns the number of values in the snapshot
+func (s PercentileSnapshot) Count() int64 {
+	var count int64
+	for _, c := range s.Counts {
+		count += c
+	}
+	return count
+}
+
+// Percentile returns an estimate of the q-th quantile of the values in the
+// snapshot, as PercentileCounter.Percentile does
+func (s PercentileSnapshot) Percentile(q float64) float64 {
+	return percentile(s.Bounds, s.Counts, q)
+}
+
+// percentile estimates the q-th quantile of the histogram with the bucket
+// bounds and counts given
+func percentile(bounds []float64, buckets []int64, q float64) float64 {
+	if q < 0 || q > 1 {
+		panic("Percentile quantile must be between 0 and 1")
+	}
+
+	var total int64
+	for _, c := range buckets {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := q * float64(total)
+	var seen int64
+	for ii, c := range buckets {
+		if c == 0 || float64(seen+c) < rank {
+			seen += c
+			continue
+		}
+		if ii == len(bounds) {
+			return bounds[ii-1]
+		}
+
+		lower := 0.0
+		if ii > 0 {
+			lower = bounds[ii-1]
+		} else if bounds[0] < 0 {
+			lower = bounds[0]
+		}
+		return lower + (bounds[ii]-lower)*(rank-float64(seen))/float64(c)
+	}
+
+	return bounds[len(bounds)-1]
+}
+
+// A Heatmap is a PercentileCounter's histogram for each partial of its
+// window, oldest first: a time by bucket matrix which encodes to JSON ready
+// for a heatmap panel.
+type Heatmap struct {
+	// Bounds holds the upper bound of each bucket. The last column of each
+	// row counts the values above the last bound.
+	Bounds []float64 `json:"bounds"`
+	// Times holds when each row's partial started, in milliseconds since the
+	// Unix epoch
+	Times []int64 `json:"times"`
+	// Counts holds a row for each partial, with a column for each bucket
+	Counts [][]int64 `json:"counts"`
+}
+
+// Heatmap returns the counter's histogram for each partial of the last
+// interval, oldest first. Partials in which nothing was observed are rows
+// of zeros, so the rows are evenly spaced in time.
+func (p *PercentileCounter) Heatmap() Heatmap {
+	epoch := p.epoch(p.now())
+	resolution := uint64(len(p.partials))
+	millis := uint64(p.interval) / resolution
+	if millis == 0 {
+		millis = 1
+	}
+
+	h := Heatmap{
+		Bounds: p.bounds,
+		Times:  make([]int64, 0, resolution),
+		Counts: make([][]int64, 0, resolution),
+	}
+	var first uint64
+	if epoch+1 > resolution {
+		first = epoch + 1 - resolution
+	}
+	for e := first; e <= epoch; e++ {
+		row := make([]int64, len(p.bounds)+1)
+		ii := e % resolution
+		if atomic.LoadUint64(&p.epochs[ii]) == e {
+			for jj := range p.partials[ii] {
+				row[jj] = p.partials[ii][jj].Value()
+			}
+		}
+		h.Times = append(h.Times, int64(e*millis))
+		h.Counts = append(h.Counts, row)
+	}
+	return h
+}
@@ -0,0 +1,195 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestPercentileCounter(intrvl time.Duration, bounds []float64, clock *manualClock, opts ...Option) *PercentileCounter {
+	return NewPercentileCounter(intrvl, bounds, append(opts, WithClock(clock))...)
+}
+
+func TestPercentileCounter(t *testing.T) {
+	clock := newManualClock()
+	p := newTestPercentileCounter(1*time.Second, []float64{10, 20, 50, 100}, clock)
+
+	if val := p.Percentile(0.5); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+
+	for i := 0; i < 50; i++ {
+		p.Observe(5)
+	}
+	for i := 0; i < 40; i++ {
+		p.Observe(15)
+	}
+	for i := 0; i < 10; i++ {
+		p.Observe(500)
+	}
+
+	if val := p.Count(); val != 100 {
+		t.Error("Expected ", val, " to equal ", 100)
+	}
+	if val := p.Percentile(0.5); val != 10 {
+		t.Error("Expected ", val, " to equal ", 10)
+	}
+	if val := p.Percentile(0.7); val != 15 {
+		t.Error("Expected ", val, " to equal ", 15)
+	}
+	// Values beyond the last bound are reported as it
+	if val := p.Percentile(0.99); val != 100 {
+		t.Error("Expected ", val, " to equal ", 100)
+	}
+}
+
+func TestPercentileCounter_Decay(t *testing.T) {
+	clock := newManualClock()
+	p := newTestPercentileCounter(1*time.Second, ExponentialBounds(1, 2, 10), clock, WithResolution(4))
+
+	p.Observe(300)
+	clock.Add(500 * time.Millisecond)
+	p.Observe(3)
+
+	if val := p.Count(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+
+	// The slow value drops out once its partial leaves the window
+	clock.Add(600 * time.Millisecond)
+	if val := p.Count(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+	if val := p.Percentile(1); val != 4 {
+		t.Error("Expected ", val, " to equal ", 4)
+	}
+
+	clock.Add(1 * time.Second)
+	if val := p.Count(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestExponentialBounds(t *testing.T) {
+	bounds := ExponentialBounds(1, 2, 4)
+	for i, expected := range []float64{1, 2, 4, 8} {
+		if bounds[i] != expected {
+			t.Error("Expected ", bounds, " to equal ", []float64{1, 2, 4, 8})
+			break
+		}
+	}
+}
+
+func BenchmarkPercentileCounter(b *testing.B) {
+	p := NewPercentileCounter(1*time.Second, ExponentialBounds(1, 2, 16))
+
+	for i := 0; i < b.N; i++ {
+		p.Observe(float64(i % 1000))
+	}
+}
+
+func TestPercentileCounter_Snapshot(t *testing.T) {
+	clock := newManualClock()
+	p := newTestPercentileCounter(1*time.Second, []float64{10, 100}, clock)
+
+	p.Observe(5)
+	p.Observe(50)
+	p.Observe(50)
+	p.Observe(1000)
+
+	s := p.Snapshot()
+	if s.Interval != time.Second {
+		t.Error("Expected ", s.Interval, " to equal ", time.Second)
+	}
+	if len(s.Bounds) != 2 || s.Bounds[0] != 10 || s.Bounds[1] != 100 {
+		t.Error("Expected ", s.Bounds, " to equal ", []float64{10, 100})
+	}
+	if len(s.Counts) != 3 || s.Counts[0] != 1 || s.Counts[1] != 2 || s.Counts[2] != 1 {
+		t.Error("Expected ", s.Counts, " to equal ", []int64{1, 2, 1})
+	}
+	if val := s.Count(); val != 4 {
+		t.Error("Expected ", val, " to equal ", 4)
+	}
+	if s.Sum != 1105 {
+		t.Error("Expected ", s.Sum, " to equal ", 1105)
+	}
+
+	// The snapshot alone is enough to estimate percentiles
+	if val, expected := s.Percentile(0.5), p.Percentile(0.5); val != expected {
+		t.Error("Expected ", val, " to equal ", expected)
+	}
+	if val := p.Sum(); val != 1105 {
+		t.Error("Expected ", val, " to equal ", 1105)
+	}
+
+	// The sum leaves the window with the values
+	clock.Add(2 * time.Second)
+	if val := p.Sum(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestPercentileCounter_StaleObserve(t *testing.T) {
+	var now uint64 = 1000000
+	p := NewPercentileCounter(1*time.Second, []float64{10, 20}, WithResolution(4), func(r *RateCounter) {
+		r.clock = func() uint64 { return now }
+	})
+	if len(p.partials) != 4 {
+		t.Fatal("Expected ", len(p.partials), " partials to equal ", 4)
+	}
+
+	// An observation for a period a whole interval older than the one the
+	// partial now holds must not clear the newer values
+	now += 1000
+	p.Observe(5)
+	now -= 1000
+	p.Observe(15)
+	now += 1000
+
+	if val := p.Count(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+	if val := p.Percentile(1); val != 10 {
+		t.Error("Expected ", val, " to equal ", 10)
+	}
+}
+
+func TestPercentileCounter_Heatmap(t *testing.T) {
+	clock := newManualClock()
+	p := newTestPercentileCounter(1*time.Second, []float64{10, 100}, clock, WithResolution(4))
+	start := clock.Now().UnixNano() / 1000000
+
+	p.Observe(5)
+	clock.Add(250 * time.Millisecond)
+	p.Observe(50)
+	p.Observe(500)
+	clock.Add(250 * time.Millisecond)
+
+	h := p.Heatmap()
+	expected := [][]int64{{0, 0, 0}, {1, 0, 0}, {0, 1, 1}, {0, 0, 0}}
+	if len(h.Counts) != len(expected) || len(h.Times) != len(expected) {
+		t.Fatal("Expected ", len(h.Counts), " rows to equal ", len(expected))
+	}
+	for ii, row := range expected {
+		if val := h.Times[ii]; val != start+int64(ii-1)*250 {
+			t.Error("Expected ", val, " to equal ", start+int64(ii-1)*250)
+		}
+		for jj, count := range row {
+			if val := h.Counts[ii][jj]; val != count {
+				t.Error("Expected ", val, " to equal ", count, " in row ", ii)
+			}
+		}
+	}
+	if len(h.Bounds) != 2 {
+		t.Error("Expected ", len(h.Bounds), " to equal ", 2)
+	}
+}
+
+func TestPercentileCounter_HeatmapNearEpoch(t *testing.T) {
+	p := newTestPercentileCounter(1*time.Second, []float64{10}, &manualClock{now: time.Unix(0, 0)}, WithResolution(4))
+	p.Observe(5)
+
+	h := p.Heatmap()
+	if len(h.Counts) != 1 || h.Counts[0][0] != 1 {
+		t.Error("Expected ", h.Counts, " to equal ", [][]int64{{1, 0}})
+	}
+}
@@ -0,0 +1,74 @@
+package ratecounter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// A Poller converts a cumulative, monotonically increasing value owned by
+// something else (NIC byte counters, database row counts, ...) into a
+// windowed rate. Call Poll periodically, typically from a time.Ticker; each
+// call records the increase since the previous one.
+type Poller struct {
+	*RateCounter
+	read func() uint64
+	// The largest value before the source wraps back to zero, or zero if it
+	// never wraps
+	max     uint64
+	last    uint64
+	started bool
+	mu      sync.Mutex
+}
+
+// NewPoller constructs a new Poller for the interval provided, reading the
+// cumulative value with read
+func NewPoller(intrvl time.Duration, read func() uint64) *Poller {
+	return &Poller{
+		RateCounter: NewRateCounter(intrvl),
+		read:        read,
+	}
+}
+
+// WithWrap declares that the source wraps back to zero after max, e.g.
+// math.MaxUint32 for a 32-bit hardware counter. Without it a decrease is
+// treated as the source restarting from zero.
+func (p *Poller) WithWrap(max uint64) *Poller {
+	p.max = max
+	return p
+}
+
+// Poll reads the source and records the increase since the last call. The
+// first call only records a starting point. It returns the increase.
+// Concurrent calls are serialized, so readings are applied in the order
+// they were taken.
+func (p *Poller) Poll() int64 {
+	p.mu.Lock()
+	value := p.read()
+	last, started := p.last, p.started
+	p.last, p.started = value, true
+	p.mu.Unlock()
+
+	if !started {
+		return 0
+	}
+
+	var delta uint64
+	switch {
+	case value >= last:
+		delta = value - last
+	case p.max > 0 && last <= p.max && value <= p.max:
+		// Wrapped around. As value < last <= max this cannot overflow.
+		delta = (p.max - last) + value + 1
+	default:
+		// The source restarted, or read past its declared maximum, so
+		// everything it has counted is new
+		delta = value
+	}
+	if delta > math.MaxInt64 {
+		delta = math.MaxInt64
+	}
+
+	p.Incr(int64(delta))
+	return int64(delta)
+}
@@ -0,0 +1,100 @@
+package ratecounter
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPoller(t *testing.T) {
+	var source uint64
+	p := NewPoller(1*time.Second, func() uint64 { return source })
+
+	check := func(expectedDelta, expectedRate int64) {
+		if delta := p.Poll(); delta != expectedDelta {
+			t.Error("Expected delta ", delta, " to equal ", expectedDelta)
+		}
+		if rate := p.Rate(); rate != expectedRate {
+			t.Error("Expected rate ", rate, " to equal ", expectedRate)
+		}
+	}
+
+	source = 100
+	check(0, 0) // Starting point
+	source = 110
+	check(10, 10)
+	source = 115
+	check(5, 15)
+	source = 3 // Restarted
+	check(3, 18)
+}
+
+func TestPoller_WithWrap(t *testing.T) {
+	source := uint64(math.MaxUint32 - 1)
+	p := NewPoller(1*time.Second, func() uint64 { return source }).WithWrap(math.MaxUint32)
+
+	p.Poll()
+	source = 2
+	if delta := p.Poll(); delta != 4 {
+		t.Error("Expected delta ", delta, " to equal ", 4)
+	}
+}
+
+func TestPoller_WithWrap_Large(t *testing.T) {
+	for _, max := range []uint64{math.MaxInt64, math.MaxUint64} {
+		source := max - 1
+		p := NewPoller(1*time.Second, func() uint64 { return source }).WithWrap(max)
+
+		p.Poll()
+		source = 2
+		if delta := p.Poll(); delta != 4 {
+			t.Error("Expected delta ", delta, " to equal ", 4, " wrapping at ", max)
+		}
+	}
+
+	// A reading past the declared maximum is a restart, not a wrap
+	source := uint64(math.MaxInt64) + 10
+	p := NewPoller(1*time.Second, func() uint64 { return source }).WithWrap(math.MaxInt64)
+	p.Poll()
+	source = 5
+	if delta := p.Poll(); delta != 5 {
+		t.Error("Expected delta ", delta, " to equal ", 5)
+	}
+
+	// Increases too large for the rate are capped, not made negative
+	source = 0
+	p = NewPoller(1*time.Second, func() uint64 { return source })
+	p.Poll()
+	source = math.MaxUint64
+	if delta := p.Poll(); delta != math.MaxInt64 {
+		t.Error("Expected delta ", delta, " to equal ", int64(math.MaxInt64))
+	}
+}
+
+func TestPoller_Concurrent(t *testing.T) {
+	// Every read returns a higher value, so applied in order the deltas
+	// add up to the final reading
+	var source uint64
+	p := NewPoller(1*time.Second, func() uint64 {
+		source++
+		return source
+	})
+	p.Poll()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(8)
+	for i := 0; i < 8; i++ {
+		go func() {
+			for j := 0; j < 100; j++ {
+				p.Poll()
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	if val := p.Rate(); val != 800 {
+		t.Error("Expected ", val, " to equal ", 800)
+	}
+}
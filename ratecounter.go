@@ -1,122 +1,601 @@
 package ratecounter
 
 import (
+	"context"
+	"math"
+	"math/bits"
 	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unsafe"
 )
 
-// A RateCounter is a thread-safe counter which returns the number of times
-// 'Incr' has been called in the last interval
+// A RateCounter is a thread-safe counter which returns the sum of the values
+// passed to 'Incr' in the last interval. Values may be negative, so a
+// RateCounter can track a net rate, such as connections opened minus closed,
+// and the rate can go negative.
+//
+// The interval is split into partials. Each covers a fixed period of time,
+// numbered from the counter's origin, and is stamped with the period it
+// holds, so a partial left over from an earlier period is recognised and
+// cleared by whoever next writes to it. Adding an event never waits, except
+// that a caller which moves the counter into a new period does that
+// period's bookkeeping, queueing behind any other caller doing the same.
+// Reads sum the partials in the window, so they take time proportional to
+// the resolution, and to the shards if WithShards is set.
 type RateCounter struct {
-	counter  Counter
-	partials []Counter
-	// The last time a partial was reset
-	resetTime uint64
-	current   int32
-	resetting bool
-	interval  uint32
+	// The fields accessed atomically come first, so they are 64-bit aligned
+	// on 32-bit platforms too
+	//
+	// The time period numbers count from, in milliseconds
+	origin uint64
+	// The latest period the counter has been moved on to
+	epoch uint64
+	// The bits of the float64 smoothed rate, updated on each rotation
+	smoothed uint64
+	// The total value of events IncrAt dropped for arriving too late
+	droppedLate Counter
+	// The highest total seen since creation or ResetMaxRate
+	maxRate int64
+
+	// The period the counter was created in
+	start uint64
+	// The partial for period e is partials[e%len(partials)]
+	partials []partial
+	interval uint32
+	// Whether partials start on wall-clock boundaries
+	aligned bool
+	// How far partial boundaries are offset, in milliseconds
+	phase uint32
+	// How late IncrAt accepts events, zero for the whole interval
+	lateness uint32
+	// Whether the interval before the current one is kept too, in a second
+	// set of partials, for PreviousRate
+	keepPrevious bool
+	// The weight of the newest rate in the smoothed rate, zero to disable
+	smoothing float64
+	// The fraction of events which are recorded, zero if all of them are
+	sampleRate float64
+	// Whether negative rates are reported as zero, set by WithClampAtZero
+	clamp bool
+	// What is being counted, for labelling exports
+	unit        string
+	description string
+	tags        map[string]string
+	// The most recent increments, if WithTrace is set
+	trace *trace
+	// The background rotation, if Start was called
+	ticker *ticker
+	// Whether to Start on construction, set by WithTicker
+	background bool
+	// Ends the ticker when done, if set by WithContext
+	ctx context.Context
+	// Held for reading while events are added, if the counter belongs to a
+	// Registry built by NewFencedRegistry, so ConsistentSnapshot can hold
+	// them off
+	fence *sync.RWMutex
+	// The number of shards behind each partial, zero if not sharded
+	shards int
+	// Called with each partial as it leaves the window, if set by OnRotate
+	onRotate func(int64, time.Time)
+	// Checked as partials rotate, guarded by the Mutex
+	watches []*Watch
+	// The period the bookkeeping has been done up to, guarded by rotation
+	rotatedTo uint64
+	// Serializes the bookkeeping, so it runs once per period and in order
+	rotation sync.Mutex
+	// Where the time comes from, in milliseconds, UnixMilli if nil
+	clock func() uint64
+	// Whether the counter was handed out by a Builder, and so may not be
+	// reconfigured
+	frozen bool
 	sync.Mutex
 }
 
-// NewRateCounter Constructs a new RateCounter
-func NewRateCounter(intrvl time.Duration) *RateCounter {
+// A partial holds the events of one period
+type partial struct {
+	// The period the value belongs to, zero if none
+	stamp uint64
+	value Counter
+	// Holds the value instead, if the counter is sharded
+	shards *ShardedCounter
+	// Pads the partial to a multiple of 8 bytes on 32-bit platforms, so the
+	// stamp of every partial in a slice stays 64-bit aligned
+	_ [(8 - unsafe.Sizeof((*ShardedCounter)(nil))) % 8]byte
+}
+
+// Incr adds val to the partial's value
+func (p *partial) Incr(val int64) {
+	if p.shards != nil {
+		p.shards.Incr(val)
+		return
+	}
+	p.value.Incr(val)
+}
+
+// Value returns the partial's value
+func (p *partial) Value() int64 {
+	if p.shards != nil {
+		return p.shards.Value()
+	}
+	return p.value.Value()
+}
+
+// Reset sets the partial's value to zero
+func (p *partial) Reset() {
+	if p.shards != nil {
+		p.shards.Reset()
+		return
+	}
+	p.value.Reset()
+}
+
+// NewRateCounter Constructs a new RateCounter, applying opts before the
+// counter is handed out so no configuration happens on a live counter
+func NewRateCounter(intrvl time.Duration, opts ...Option) *RateCounter {
 	rc := &RateCounter{
-		partials:  make([]Counter, 20),
-		resetTime: UnixMilli(),
-		interval:  uint32(intrvl.Nanoseconds() / 1000000),
+		partials: make([]partial, 20),
+		interval: uint32(intrvl.Nanoseconds() / 1000000),
+	}
+
+	for _, opt := range opts {
+		opt(rc)
+	}
+	rc.partials = rc.newPartials(len(rc.partials))
+	rc.setOrigin(rc.now())
+	if rc.ctx != nil {
+		context.AfterFunc(rc.ctx, func() { rc.Close() })
+	}
+	if rc.background {
+		rc.Start()
 	}
 
 	return rc
 }
 
-func (r *RateCounter) updatePartials(interval uint32, val int64) {
-	// The number of time slices we keep within the interval
-	resolution := len(r.partials)
-	// The last time a partial was reset
-	resetTime := atomic.LoadUint64(&r.resetTime)
-	now := UnixMilli()
-	timeDiff := float32(now - resetTime)
-
-	// The interval of time a partial is responsible for
-	partialInterval := float32(interval) / float32(resolution)
-	// The next partial to drop
-
-	//fmt.Printf("now: %v rt: %v td: %v, pi: %v\n", now, resetTime, timeDiff, partialInterval)
-
-	// We are beyond at least one partial interval
-	if timeDiff > partialInterval {
-		// Make sure only one of us does the updating
-		r.Lock()
-		if !r.resetting {
-			r.resetting = true
-			r.Unlock()
-			defer func() {
-				r.Lock()
-				r.resetting = false
-				r.Unlock()
-			}()
-		} else {
-			r.Unlock()
-			// Someone else is doing it
-			return
-		}
+// setOrigin numbers periods so that one starts at now, or on the wall-clock
+// boundaries if the counter is aligned, and moves the counter on to the one
+// containing now
+func (r *RateCounter) setOrigin(now uint64) {
+	millis := r.partialMillis()
+	phase := uint64(r.phase) % millis
 
+	if r.aligned {
+		r.origin = phase
 	} else {
-		// No need to update the partials
-		return
+		// Start a couple of intervals back, so events IncrAt is given from
+		// before the counter was created still fall in their own periods
+		back := phase + 2*uint64(r.interval)
+		if now < back {
+			back = now
+		}
+		r.origin = now - back
+	}
+	r.start = r.period(now)
+	r.epoch = r.start
+	r.rotatedTo = r.start
+}
+
+// retains reports whether a second interval of partials is kept, so those
+// which have left the window can still be read
+func (r *RateCounter) retains() bool {
+	return r.keepPrevious || r.onRotate != nil
+}
+
+// resolution returns the number of partials the interval is split into
+func (r *RateCounter) resolution() int {
+	if r.retains() {
+		return len(r.partials) / 2
 	}
+	return len(r.partials)
+}
 
-	current := atomic.LoadInt32(&r.current)
+// period returns the number of the period containing now, in milliseconds.
+// Periods count from firstPeriod, so that a stamp of zero means unused and
+// there are always two intervals' worth of earlier periods to look back on.
+func (r *RateCounter) period(now uint64) uint64 {
+	origin := atomic.LoadUint64(&r.origin)
+	if now < origin {
+		return 1
+	}
 
-	// We can only get here if we are updating the partials. The resetting flag should protect things
-	// such that only one can get in at a time
-	for ii := 0; timeDiff > partialInterval && ii < resolution; ii++ {
-		// We need to do this potentially many times if there hasn't been an update for a while
-		timeDiff = timeDiff - partialInterval
+	interval := uint64(r.interval)
+	if interval == 0 {
+		interval = 1
+	}
+	hi, lo := bits.Mul64(now-origin, uint64(r.resolution()))
+	e, _ := bits.Div64(hi, lo, interval)
+	return e + r.firstPeriod()
+}
 
-		next := (int(current) + 1) % resolution
+// firstPeriod returns the number of the period starting at the origin
+func (r *RateCounter) firstPeriod() uint64 {
+	return uint64(len(r.partials)) + 1
+}
 
-		// Remove the last partial from the current count
-		r.counter.Incr(-1 * r.partials[next].Value())
-		//fmt.Printf("\tcurrent %v, next: %v, dec rate: %v dropped: %v\n", current, next, r.counter, r.partials[next].Value())
-		// Reset the count in that partial to make ready for next
-		r.partials[next].Reset()
-		// Set the reset partial as the current partial
+// periodStart returns when period e began, in milliseconds
+func (r *RateCounter) periodStart(e uint64) uint64 {
+	origin := atomic.LoadUint64(&r.origin)
+	if e < r.firstPeriod() {
+		return origin
+	}
+
+	res := uint64(r.resolution())
+	return origin + ((e-r.firstPeriod())*uint64(r.interval)+res-1)/res
+}
 
-		current = int32(next)
+// advance moves the counter on to the period containing now and returns
+// it. The caller which moves it on does the bookkeeping for the periods
+// which ended.
+func (r *RateCounter) advance(now uint64) uint64 {
+	e := r.period(now)
+	last := atomic.LoadUint64(&r.epoch)
+	if e <= last || !atomic.CompareAndSwapUint64(&r.epoch, last, e) {
+		return e
 	}
-	atomic.StoreInt32(&r.current, int32(current))
+	r.catchUp()
+	return e
+}
+
+// catchUp does the bookkeeping for every period which has ended since it
+// last ran. Callers which move the counter on at the same time queue here,
+// and whoever runs first does the periods of both, so the bookkeeping never
+// runs twice at once or out of order. Only callers which moved the epoch
+// on wait, at most once per period.
+func (r *RateCounter) catchUp() {
+	r.rotation.Lock()
+	defer r.rotation.Unlock()
+
+	e := atomic.LoadUint64(&r.epoch)
+	if last := r.rotatedTo; last != 0 && e > last {
+		r.rotated(last, e)
+	}
+	r.rotatedTo = e
+}
+
+// rotated records the maximum and smoothed rates as the counter moved from
+// period last to e, hands the partials which left the window to the
+// OnRotate callback and checks the watches. The caller must hold rotation.
+func (r *RateCounter) rotated(last, e uint64) {
+	res := uint64(r.resolution())
+
+	// After res periods every total is zero
+	steps := e - last
+	if steps > res {
+		steps = res
+	}
+	for k := last; k < last+steps; k++ {
+		// The total is at its highest for a period just before it ends
+		r.observeMax(r.total(k))
+
+		if r.smoothing > 0 {
+			smoothed := math.Float64frombits(atomic.LoadUint64(&r.smoothed))
+			smoothed += r.smoothing * (float64(r.total(k+1)) - smoothed)
+			atomic.StoreUint64(&r.smoothed, math.Float64bits(smoothed))
+		}
+
+		if r.onRotate != nil {
+			expired := before(k+1, res)
+			// Partials from before the counter was created are only
+			// reported if IncrAt credited them with something
+			if dropped := r.sum(expired, expired); expired >= r.start || dropped != 0 {
+				at := time.Unix(0, int64(r.periodStart(k+1))*int64(time.Millisecond))
+				r.onRotate(dropped, at)
+			}
+		}
+	}
+
+	if r.smoothing > 0 && e-last > steps {
+		smoothed := math.Float64frombits(atomic.LoadUint64(&r.smoothed))
+		smoothed *= math.Pow(1-r.smoothing, float64(e-last-steps))
+		atomic.StoreUint64(&r.smoothed, math.Float64bits(smoothed))
+	}
+
+	r.notify(e)
+}
+
+// partialFor returns the partial for period e, clearing it first if it
+// holds an earlier period. Only the caller whose CompareAndSwap wins clears
+// it, so nothing is dropped twice and nobody waits.
+func (r *RateCounter) partialFor(e uint64) *partial {
+	p := &r.partials[e%uint64(len(r.partials))]
+
+	for {
+		stamp := atomic.LoadUint64(&p.stamp)
+		if stamp >= e {
+			// Either current, or this caller is behind one which has
+			// already moved on, in which case the event counts as now
+			return p
+		}
+
+		old := p.Value()
+		if atomic.CompareAndSwapUint64(&p.stamp, stamp, e) {
+			// Events which land in between stay, and count towards e
+			p.Incr(-old)
+			return p
+		}
+	}
+}
+
+// sum returns the total of the partials for periods from to through
+func (r *RateCounter) sum(from, through uint64) int64 {
+	var total int64
+	for ii := range r.partials {
+		if stamp := atomic.LoadUint64(&r.partials[ii].stamp); stamp != 0 && stamp >= from && stamp <= through {
+			total += r.partials[ii].Value()
+		}
+	}
+	return total
+}
+
+// total returns the number of events in the interval ending in period e
+func (r *RateCounter) total(e uint64) int64 {
+	return r.sum(before(e+1, uint64(r.resolution())), e)
+}
+
+// before returns n periods before e, or zero if there are none
+func before(e, n uint64) uint64 {
+	if n >= e {
+		return 0
+	}
+	return e - n
+}
+
+// now returns the current time in milliseconds since the epoch
+func (r *RateCounter) now() uint64 {
+	if r.clock != nil {
+		return r.clock()
+	}
+	return UnixMilli()
+}
 
-	atomic.StoreUint64(&r.resetTime, now)
+// partialMillis returns the whole number of milliseconds each partial covers
+func (r *RateCounter) partialMillis() uint64 {
+	millis := uint64(r.interval) / uint64(r.resolution())
+	if millis == 0 {
+		return 1
+	}
+	return millis
 }
 
-// WithResolution determines the minimum resolution of this counter, default is 20
+// WithResolution determines the minimum resolution of this counter, default is 20.
+// It discards the counts recorded so far and is not safe to call while the
+// counter is in use; configure new counters with the WithResolution option
+// or a Builder instead. It panics on a counter built by a Builder.
 func (r *RateCounter) WithResolution(resolution int) *RateCounter {
 	if resolution < 1 {
 		panic("RateCounter resolution cannot be less than 1")
 	}
+	if r.frozen {
+		panic("RateCounter built by a Builder cannot be reconfigured")
+	}
 
-	r.partials = make([]Counter, resolution)
-	r.current = 0
+	r.partials = r.newPartials(resolution)
+	r.setOrigin(r.now())
 
 	return r
 }
 
+// newPartials returns the partials for a resolution, doubled if they are
+// retained and sharded if the counter is
+func (r *RateCounter) newPartials(resolution int) []partial {
+	if r.retains() {
+		resolution *= 2
+	}
+
+	partials := make([]partial, resolution)
+	if r.shards > 0 {
+		for ii := range partials {
+			partials[ii].shards = NewShardedCounter(r.shards)
+		}
+	}
+	return partials
+}
+
 // Incr Add an event into the RateCounter
 func (r *RateCounter) Incr(val int64) {
+	r.incr(val)
+	if r.trace != nil {
+		r.trace.add(r.now(), val, "")
+	}
+}
+
+func (r *RateCounter) incr(val int64) {
+	r.add(r.advance(r.now()), val)
+}
+
+// add credits val to the partial for period e, inside the registry's fence
+// if the counter belongs to a fenced registry
+func (r *RateCounter) add(e uint64, val int64) {
+	if r.fence != nil {
+		r.fence.RLock()
+		defer r.fence.RUnlock()
+	}
+	r.partialFor(e).Incr(val)
+}
+
+// IncrAt Add an event which happened at t into the RateCounter, crediting
+// the partial covering t. Events older than the interval, or the lateness
+// set with WithLateness, are dropped and added to DroppedLate, as are
+// events from before 1970, such as the zero time. Events in the future are
+// counted as happening now.
+func (r *RateCounter) IncrAt(t time.Time, val int64) {
+	now := r.now()
+	e := r.advance(now)
+
+	if t.Before(time.Unix(0, 0)) {
+		r.droppedLate.Incr(val)
+		return
+	}
+	at := uint64(t.Unix())*1000 + uint64(t.Nanosecond()/1000000)
+
+	if r.lateness > 0 && at+uint64(r.lateness) < now {
+		r.droppedLate.Incr(val)
+		return
+	}
+
+	period := r.period(at)
+	if period > e {
+		period = e
+	}
+	if period+uint64(r.resolution()) <= e {
+		r.droppedLate.Incr(val)
+		return
+	}
+
+	r.add(period, val)
+}
+
+// DroppedLate Return the total value of events IncrAt has dropped for
+// arriving too late
+func (r *RateCounter) DroppedLate() int64 {
+	return r.droppedLate.Value()
+}
 
-	r.counter.Incr(val)
-	r.updatePartials(r.interval, val)
-	current := atomic.LoadInt32(&r.current)
-	r.partials[current].Incr(val)
+// IncrAndRate Add an event into the RateCounter and return the number of
+// events in the last interval, including this one. It moves the counter on
+// once for both, rather than once for Incr and again for Rate, but still
+// sums the partials as Rate does.
+func (r *RateCounter) IncrAndRate(val int64) int64 {
+	e := r.advance(r.now())
+	r.add(e, val)
+	rate := r.total(e)
+	r.observeMax(rate)
+	return r.scale(rate)
 }
 
-// Rate Return the current number of events in the last interval
+// Rate Return the current number of events in the last interval, scaled up
+// by the sample rate if one was set with WithSampleRate. It sums the
+// partials in the window, so it takes time proportional to the resolution.
 func (r *RateCounter) Rate() int64 {
-	r.updatePartials(r.interval, 0)
-	return r.counter.Value()
+	rate := r.total(r.advance(r.now()))
+	r.observeMax(rate)
+	return r.scale(rate)
+}
+
+// observeMax raises the recorded maximum to rate if it is higher
+func (r *RateCounter) observeMax(rate int64) {
+	for {
+		max := atomic.LoadInt64(&r.maxRate)
+		if rate <= max || atomic.CompareAndSwapInt64(&r.maxRate, max, rate) {
+			return
+		}
+	}
+}
+
+// MaxRate Return the highest rate seen since the counter was created or
+// ResetMaxRate was called. It is updated as partials are dropped and
+// whenever the rate is read.
+func (r *RateCounter) MaxRate() int64 {
+	r.Rate()
+	return r.scale(atomic.LoadInt64(&r.maxRate))
+}
+
+// ResetMaxRate Start tracking the highest rate afresh, from the current rate
+func (r *RateCounter) ResetMaxRate() {
+	atomic.StoreInt64(&r.maxRate, r.total(r.advance(r.now())))
+}
+
+// Reset Zero the counter, as if it had just been created with the same
+// configuration: the rate, every partial, the previous window, the smoothed
+// and maximum rates and DroppedLate are cleared. Events recorded while
+// Reset runs may or may not be kept.
+func (r *RateCounter) Reset() {
+	for ii := range r.partials {
+		// Clearing the value before the stamp means a writer which reads
+		// the value in between has nothing to drop
+		r.partials[ii].Reset()
+		atomic.StoreUint64(&r.partials[ii].stamp, 0)
+	}
+	r.droppedLate.Reset()
+	atomic.StoreInt64(&r.maxRate, 0)
+	atomic.StoreUint64(&r.smoothed, 0)
+}
+
+// scale converts a sampled count into an estimate for the full population,
+// and clamps it at zero if the counter was built with WithClampAtZero
+func (r *RateCounter) scale(val int64) int64 {
+	if r.clamp && val < 0 {
+		return 0
+	}
+	if r.sampleRate == 0 {
+		return val
+	}
+	return int64(math.Round(float64(val) / r.sampleRate))
+}
+
+// RawRate Return the current number of events in the last interval. It is
+// the same as Rate, for symmetry with SmoothedRate.
+func (r *RateCounter) RawRate() int64 {
+	return r.Rate()
+}
+
+// SmoothedRate Return an exponentially weighted moving average of the rate,
+// updated each time a partial is dropped, for display. It is the same as
+// Rate unless the counter was built with WithSmoothing.
+func (r *RateCounter) SmoothedRate() float64 {
+	if r.smoothing == 0 {
+		return float64(r.Rate())
+	}
+
+	r.advance(r.now())
+	smoothed := math.Float64frombits(atomic.LoadUint64(&r.smoothed))
+	if r.sampleRate > 0 {
+		smoothed /= r.sampleRate
+	}
+	if r.clamp && smoothed < 0 {
+		return 0
+	}
+	return smoothed
+}
+
+// PreviousRate Return the number of events in the interval before the last
+// one. It is always zero unless the counter was built with
+// WithPreviousWindow.
+func (r *RateCounter) PreviousRate() int64 {
+	_, previous := r.windows()
+	return r.scale(previous)
+}
+
+// windows returns the totals of the last interval and, if they are kept,
+// the one before it
+func (r *RateCounter) windows() (int64, int64) {
+	e := r.advance(r.now())
+	if !r.keepPrevious {
+		return r.total(e), 0
+	}
+
+	res := uint64(r.resolution())
+	return r.total(e), r.sum(before(e+1, 2*res), before(e, res))
+}
+
+// ChangePercent Return how much the rate over the last interval has changed
+// relative to the interval before it, e.g. -40 when traffic is down 40%. It
+// returns 0 when the previous interval had no events.
+func (r *RateCounter) ChangePercent() float64 {
+	current, previous := r.windows()
+
+	if previous == 0 {
+		return 0 // Avoid division by zero
+	}
+
+	return float64(current-previous) / float64(previous) * 100
+}
+
+// Unit returns what the counter counts, e.g. "requests" or "bytes"
+func (r *RateCounter) Unit() string {
+	return r.unit
+}
+
+// Description returns the counter's human readable description
+func (r *RateCounter) Description() string {
+	return r.description
+}
+
+// Tags returns a copy of the counter's dimensional tags
+func (r *RateCounter) Tags() map[string]string {
+	return r.tagsInto(nil)
 }
 
 func (r *RateCounter) String() string {
@@ -3,6 +3,7 @@ package ratecounter
 import (
 	"fmt"
 	"io/ioutil"
+	"sync"
 	"testing"
 	"time"
 )
@@ -265,3 +266,282 @@ func Benchmark_TimeNowAndAdd(b *testing.B) {
 	}
 	fmt.Fprintln(ioutil.Discard, a)
 }
+
+func TestRateCounter_IncrAndRate(t *testing.T) {
+	interval := 500 * time.Millisecond
+	r := NewRateCounter(interval)
+
+	if val := r.IncrAndRate(1); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+	if val := r.IncrAndRate(2); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+	time.Sleep(2 * interval)
+	if val := r.IncrAndRate(4); val != 4 {
+		t.Error("Expected ", val, " to equal ", 4)
+	}
+	if val := r.Rate(); val != 4 {
+		t.Error("Expected ", val, " to equal ", 4)
+	}
+}
+
+func BenchmarkRateCounter_IncrAndRate(b *testing.B) {
+	interval := 1000 * time.Millisecond
+	r := NewRateCounter(interval)
+
+	for i := 0; i < b.N; i++ {
+		r.IncrAndRate(1)
+	}
+}
+
+func TestRateCounter_IncrAt(t *testing.T) {
+	interval := 500 * time.Millisecond
+	r := NewRateCounter(interval).WithResolution(5)
+
+	check := func(expected int64) {
+		val := r.Rate()
+		if val != expected {
+			t.Error("Expected ", val, " to equal ", expected)
+		}
+	}
+
+	// Wait until a few partials have passed so there is history to credit
+	time.Sleep(250 * time.Millisecond)
+	r.Incr(0)
+
+	r.IncrAt(time.Now(), 1)
+	r.IncrAt(time.Now().Add(-150*time.Millisecond), 2)
+	r.IncrAt(time.Now().Add(-2*interval), 4) // Too late, dropped
+	r.IncrAt(time.Now().Add(time.Hour), 8)   // In the future, counted now
+	check(11)
+
+	buckets := r.Buckets()
+	if buckets[4] != 9 {
+		t.Error("Expected newest bucket ", buckets[4], " to equal ", 9)
+	}
+
+	// The late event expires before the ones that happened "now"
+	time.Sleep(interval - 150*time.Millisecond + 50*time.Millisecond)
+	check(9)
+	time.Sleep(2 * interval)
+	check(0)
+}
+
+func TestRateCounterLargeValues(t *testing.T) {
+	r := NewRateCounter(1 * time.Second)
+
+	r.Incr(5 * 1000 * 1000 * 1000)
+	r.Incr(5 * 1000 * 1000 * 1000)
+	if val := r.Rate(); val != 10*1000*1000*1000 {
+		t.Error("Expected ", val, " to equal ", int64(10*1000*1000*1000))
+	}
+}
+
+func TestRateCounterNegative(t *testing.T) {
+	interval := 200 * time.Millisecond
+	r := NewRateCounter(interval).WithResolution(4)
+
+	check := func(expected int64) {
+		val := r.Rate()
+		if val != expected {
+			t.Error("Expected ", val, " to equal ", expected)
+		}
+	}
+
+	// Connections opened minus closed
+	r.Incr(3)
+	r.Incr(-1)
+	check(2)
+
+	// Closes in a later partial take the window negative once the opens
+	// have expired
+	time.Sleep(110 * time.Millisecond)
+	r.Incr(-4)
+	check(-2)
+	time.Sleep(110 * time.Millisecond)
+	check(-4)
+	time.Sleep(2 * interval)
+	check(0)
+}
+
+func TestRateCounter_MaxRate(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounterWithOptions(200*time.Millisecond, WithResolution(4), WithClock(clock))
+
+	check := func(expected int64) {
+		val := r.MaxRate()
+		if val != expected {
+			t.Error("Expected max ", val, " to equal ", expected)
+		}
+	}
+
+	check(0)
+	r.Incr(3)
+	clock.Add(60 * time.Millisecond)
+	r.Incr(4)
+	clock.Add(60 * time.Millisecond)
+
+	// The peak is reached between reads, and found when the partial turns
+	check(7)
+
+	clock.Add(time.Second)
+	if val := r.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+	check(7)
+
+	r.ResetMaxRate()
+	check(0)
+	r.Incr(2)
+	check(2)
+}
+
+func TestRateCounter_Reset(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounterWithOptions(200*time.Millisecond, WithResolution(4), WithPreviousWindow(), WithClock(clock))
+
+	r.Incr(3)
+	clock.Add(250 * time.Millisecond)
+	r.Incr(4)
+	r.IncrAt(clock.Now().Add(-time.Second), 5)
+
+	r.Reset()
+
+	if val := r.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+	if val := r.PreviousRate(); val != 0 {
+		t.Error("Expected previous ", val, " to equal ", 0)
+	}
+	if val := r.MaxRate(); val != 0 {
+		t.Error("Expected max ", val, " to equal ", 0)
+	}
+	if val := r.DroppedLate(); val != 0 {
+		t.Error("Expected dropped ", val, " to equal ", 0)
+	}
+	for _, b := range r.Buckets() {
+		if b != 0 {
+			t.Error("Expected ", r.Buckets(), " to be empty")
+			break
+		}
+	}
+
+	// The window starts afresh from the reset
+	r.Incr(2)
+	clock.Add(150 * time.Millisecond)
+	if val := r.Rate(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+}
+
+func TestRateCounter_Incr_NoLostIncrementsAcrossRotation(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounter(1*time.Second, WithResolution(10), WithClock(clock))
+
+	const writers, incrs = 8, 2000
+	done := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(writers)
+	for i := 0; i < writers; i++ {
+		go func() {
+			for j := 0; j < incrs; j++ {
+				r.Incr(1)
+			}
+			wg.Done()
+		}()
+	}
+
+	// Move the clock through several partials, but not out of the window,
+	// while the writers run
+	go func() {
+		for ii := 0; ii < 800; ii++ {
+			clock.Add(1 * time.Millisecond)
+		}
+		close(done)
+	}()
+
+	wg.Wait()
+	<-done
+
+	if val := r.Rate(); val != writers*incrs {
+		t.Error("Expected ", val, " to equal ", writers*incrs)
+	}
+
+	var total int64
+	for _, b := range r.Buckets() {
+		total += b
+	}
+	if total != writers*incrs {
+		t.Error("Expected bucket total ", total, " to equal ", writers*incrs)
+	}
+}
+
+func TestRateCounter_Incr_NoLostIncrementsWithTicker(t *testing.T) {
+	// 10ms partials rotate many times during the test, on the ticker and on
+	// every read, but nothing leaves the window
+	r := NewRateCounter(10*time.Second, WithResolution(1000), WithTicker())
+	defer r.Stop()
+
+	const writers = 4
+	deadline := time.Now().Add(200 * time.Millisecond)
+	counts := make([]int64, writers)
+	wg := &sync.WaitGroup{}
+	wg.Add(writers + 1)
+	for i := 0; i < writers; i++ {
+		go func(i int) {
+			for time.Now().Before(deadline) {
+				r.Incr(1)
+				counts[i]++
+			}
+			wg.Done()
+		}(i)
+	}
+	go func() {
+		for time.Now().Before(deadline) {
+			r.Rate()
+		}
+		wg.Done()
+	}()
+	wg.Wait()
+
+	var expected int64
+	for _, c := range counts {
+		expected += c
+	}
+	if val := r.Rate(); val != expected {
+		t.Error("Expected ", val, " to equal ", expected)
+	}
+}
+
+func TestRateCounter_partialFor_LaggingWriter(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounter(1*time.Second, WithResolution(4), WithClock(clock))
+
+	e := r.advance(r.now())
+	r.partialFor(e + 4).Incr(2)
+
+	// A writer still holding the earlier period shares the newer partial
+	// rather than clearing it or being dropped
+	r.partialFor(e).Incr(1)
+	if val := r.sum(e+4, e+4); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+}
+
+func TestRateCounter_IncrAt_BeforeEpoch(t *testing.T) {
+	for _, lateness := range []time.Duration{0, 100 * time.Millisecond} {
+		r := NewRateCounter(1*time.Second, WithLateness(lateness))
+
+		r.IncrAt(time.Time{}, 16)
+		r.IncrAt(time.Unix(-1, 0), 4)
+		r.IncrAt(time.Now(), 1)
+
+		if val := r.Rate(); val != 1 {
+			t.Error("Expected ", val, " to equal ", 1)
+		}
+		if val := r.DroppedLate(); val != 20 {
+			t.Error("Expected dropped ", val, " to equal ", 20)
+		}
+	}
+}
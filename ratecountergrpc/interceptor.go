@@ -0,0 +1,75 @@
+// Package ratecountergrpc provides gRPC server interceptors which count
+// calls in a ratecounter Registry by full method name, so gRPC services get
+// rolling QPS and error rates without hand-rolled instrumentation.
+//
+//	calls := ratecounter.NewRegistry(time.Minute)
+//	srv := grpc.NewServer(
+//		grpc.UnaryInterceptor(ratecountergrpc.UnaryServerInterceptor(calls, ratecountergrpc.WithCodes())),
+//		grpc.StreamInterceptor(ratecountergrpc.StreamServerInterceptor(calls)),
+//	)
+package ratecountergrpc
+
+import (
+	"context"
+
+	"github.com/paulbellamy/ratecounter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// An Option configures an interceptor
+type Option func(*config)
+
+type config struct {
+	codes bool
+}
+
+// WithCodes also counts each call by its status code, in a counter named
+// after the method and code, e.g. "/pkg.Service/Get NotFound"
+func WithCodes() Option {
+	return func(c *config) {
+		c.codes = true
+	}
+}
+
+// count counts a finished call to method which returned err
+func (c *config) count(g *ratecounter.Registry, method string, err error) {
+	g.Get(method).Incr(1)
+	if c.codes {
+		g.Get(method + " " + status.Code(err).String()).Incr(1)
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// UnaryServerInterceptor returns an interceptor counting each unary call in
+// the registry counter named after its full method, e.g.
+// "/pkg.Service/Get"
+func UnaryServerInterceptor(g *ratecounter.Registry, opts ...Option) grpc.UnaryServerInterceptor {
+	c := newConfig(opts)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		c.count(g, info.FullMethod, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor counting each streaming
+// call in the registry counter named after its full method, once the
+// stream ends
+func StreamServerInterceptor(g *ratecounter.Registry, opts ...Option) grpc.StreamServerInterceptor {
+	c := newConfig(opts)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		c.count(g, info.FullMethod, err)
+		return err
+	}
+}
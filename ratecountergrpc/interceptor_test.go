@@ -0,0 +1,65 @@
+package ratecountergrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	intercept := UnaryServerInterceptor(g, WithCodes())
+	info := &grpc.UnaryServerInfo{FullMethod: "/pkg.Service/Get"}
+
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "found", nil
+	}
+	missing := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "no such thing")
+	}
+
+	resp, err := intercept(context.Background(), nil, info, ok)
+	if resp != "found" || err != nil {
+		t.Error("Expected the handler's response, got ", resp, err)
+	}
+	intercept(context.Background(), nil, info, ok)
+	if _, err := intercept(context.Background(), nil, info, missing); status.Code(err) != codes.NotFound {
+		t.Error("Expected ", err, " to be passed through")
+	}
+
+	for name, expected := range map[string]int64{
+		"/pkg.Service/Get":          3,
+		"/pkg.Service/Get OK":       2,
+		"/pkg.Service/Get NotFound": 1,
+	} {
+		if val := g.Get(name).Rate(); val != expected {
+			t.Error("Expected ", name, " ", val, " to equal ", expected)
+		}
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	intercept := StreamServerInterceptor(g)
+	info := &grpc.StreamServerInfo{FullMethod: "/pkg.Service/Watch", IsServerStream: true}
+
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		return nil
+	}
+	if err := intercept(nil, nil, info, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	if val := g.Get("/pkg.Service/Watch").Rate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+	// Codes are only counted when asked for
+	if g.Len() != 1 {
+		t.Error("Expected ", g.Len(), " to equal ", 1)
+	}
+}
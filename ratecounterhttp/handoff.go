@@ -0,0 +1,57 @@
+package ratecounterhttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+// HandoffHandler returns an http.Handler serving a snapshot of every counter
+// in the registry, all taken at the same instant, for the process replacing
+// this one to fetch with Handoff as it starts. Serve it while draining, so
+// a rolling deploy carries the rates over rather than zeroing them and
+// tripping low-traffic alerts.
+func HandoffHandler(g *ratecounter.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		writeJSON(w, g.ConsistentSnapshot())
+	})
+}
+
+// Handoff fetches the snapshot a HandoffHandler serves at url, with client,
+// http.DefaultClient if nil, and merges it into g with Registry.Merge. Any
+// events already counted in g are kept.
+func Handoff(ctx context.Context, client *http.Client, url string, g *ratecounter.Registry) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ratecounterhttp: unexpected status %s from %s", resp.Status, url)
+	}
+
+	var snap ratecounter.RegistrySnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snap); err != nil {
+		return err
+	}
+	g.Merge(snap)
+	return nil
+}
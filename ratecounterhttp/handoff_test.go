@@ -0,0 +1,46 @@
+package ratecounterhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+func TestHandoff(t *testing.T) {
+	draining := ratecounter.NewRegistry(1 * time.Minute)
+	draining.Get("GET /users").Incr(12)
+	draining.Get("POST /orders").Incr(3)
+
+	srv := httptest.NewServer(HandoffHandler(draining))
+	defer srv.Close()
+
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	g.Get("GET /users").Incr(1)
+	if err := Handoff(context.Background(), nil, srv.URL, g); err != nil {
+		t.Fatal(err)
+	}
+
+	if val := g.Get("GET /users").Rate(); val != 13 {
+		t.Error("Expected ", val, " to equal ", 13)
+	}
+	if val := g.Get("POST /orders").Rate(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+}
+
+func TestHandoff_Status(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	if err := Handoff(context.Background(), nil, srv.URL, g); err == nil {
+		t.Error("Expected an error for a 404")
+	}
+	if g.Len() != 0 {
+		t.Error("Expected ", g.Len(), " to equal ", 0)
+	}
+}
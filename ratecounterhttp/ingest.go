@@ -0,0 +1,197 @@
+// Package ratecounterhttp connects ratecounter Registries to HTTP.
+package ratecounterhttp
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+// MaxIngestBytes is the largest request body IngestHandler accepts
+const MaxIngestBytes = 1 << 20
+
+// An Increment is one entry in a batch sent to IngestHandler
+type Increment struct {
+	// Name is the registry counter to increment
+	Name string `json:"name"`
+	// Value is passed to Incr
+	Value int64 `json:"value"`
+	// Timestamp is when the event happened, if it is not now. Late events
+	// are credited with IncrAt.
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	// Source identifies the sender for skew tracking, the request's remote
+	// host if empty
+	Source string `json:"source,omitempty"`
+}
+
+// An IngestOption configures IngestHandler
+type IngestOption func(*ingestConfig)
+
+type ingestConfig struct {
+	skew        *SkewTracker
+	allow       func(name string) bool
+	maxCounters int
+}
+
+// AllowNames lets IngestHandler create the counters named, as well as
+// incrementing those already in the registry
+func AllowNames(names ...string) IngestOption {
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+	return AllowFunc(func(name string) bool { return allowed[name] })
+}
+
+// AllowFunc lets IngestHandler create the counters for which allow returns
+// true, as well as incrementing those already in the registry
+func AllowFunc(allow func(name string) bool) IngestOption {
+	return func(c *ingestConfig) {
+		c.allow = allow
+	}
+}
+
+// MaxCounters caps the counters IngestHandler creates, rejecting batches
+// which would take the registry over n. Without AllowNames or AllowFunc it
+// lets any new name be created while there is room.
+func MaxCounters(n int) IngestOption {
+	return func(c *ingestConfig) {
+		c.maxCounters = n
+	}
+}
+
+// WithSkewTracker makes IngestHandler estimate each source's clock skew
+// from the timestamps it sends with s, correcting them if s.Correct is set
+func WithSkewTracker(s *SkewTracker) IngestOption {
+	return func(c *ingestConfig) {
+		c.skew = s
+	}
+}
+
+// IngestHandler returns an http.Handler which accepts a POSTed JSON array of
+// Increments and applies them to the registry, so sidecars, scripts and
+// non-Go processes can feed counters in this process:
+//
+//	[{"name": "jobs", "value": 3}, {"name": "jobs", "value": 1, "timestamp": "2018-10-08T12:00:00Z"}]
+//
+// Clients are not trusted to create counters: a name must already be in
+// the registry unless AllowNames, AllowFunc or MaxCounters admits it, and a
+// batch with any other name is rejected. A batch is validated before any
+// of it is applied.
+func IngestHandler(g *ratecounter.Registry, opts ...IngestOption) http.Handler {
+	c := &ingestConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	s := c.skew
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var batch []Increment
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxIngestBytes)).Decode(&batch); err != nil {
+			http.Error(w, "invalid batch: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := c.validate(g, batch); err != "" {
+			http.Error(w, "invalid batch: "+err, http.StatusBadRequest)
+			return
+		}
+
+		var skews map[string]time.Duration
+		if s != nil {
+			skews = observeSkews(s, batch, r)
+		}
+
+		for _, inc := range batch {
+			if inc.Timestamp != nil {
+				at := *inc.Timestamp
+				if s != nil {
+					at = s.correct(at, skews[source(inc, r)])
+				}
+				g.Get(inc.Name).IncrAt(at, inc.Value)
+			} else {
+				g.Get(inc.Name).Incr(inc.Value)
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// IngestHandlerWithSkew is IngestHandler with WithSkewTracker(s). A nil s
+// tracks nothing.
+func IngestHandlerWithSkew(g *ratecounter.Registry, s *SkewTracker, opts ...IngestOption) http.Handler {
+	return IngestHandler(g, append(opts, WithSkewTracker(s))...)
+}
+
+// validate returns why batch cannot be applied to g, or "" if it can
+func (c *ingestConfig) validate(g *ratecounter.Registry, batch []Increment) string {
+	created := map[string]bool{}
+	for _, inc := range batch {
+		if inc.Name == "" {
+			return "missing name"
+		}
+		if _, ok := g.Lookup(inc.Name); ok || created[inc.Name] {
+			continue
+		}
+
+		switch {
+		case c.allow != nil && c.allow(inc.Name):
+		case c.allow == nil && c.maxCounters > 0:
+		default:
+			return "unknown counter " + strconv.Quote(inc.Name)
+		}
+		created[inc.Name] = true
+	}
+
+	if c.maxCounters > 0 && len(created) > 0 && g.Len()+len(created) > c.maxCounters {
+		return "too many counters"
+	}
+	return ""
+}
+
+// observeSkews feeds the newest timestamp from each source in batch to s and
+// returns their updated skews
+func observeSkews(s *SkewTracker, batch []Increment, r *http.Request) map[string]time.Duration {
+	newest := map[string]time.Time{}
+	for _, inc := range batch {
+		if inc.Timestamp == nil {
+			continue
+		}
+		src := source(inc, r)
+		if t, ok := newest[src]; !ok || inc.Timestamp.After(t) {
+			newest[src] = *inc.Timestamp
+		}
+	}
+
+	skews := make(map[string]time.Duration, len(newest))
+	for src, t := range newest {
+		skews[src] = s.Observe(src, t)
+	}
+	return skews
+}
+
+// source returns who sent inc
+func source(inc Increment, r *http.Request) string {
+	if inc.Source != "" {
+		return inc.Source
+	}
+	return remoteHost(r)
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,101 @@
+package ratecounterhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+func TestIngestHandler(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	h := IngestHandler(g, AllowNames("jobs", "errors"))
+
+	recent := time.Now().Add(-10 * time.Second).UTC().Format(time.RFC3339Nano)
+	stale := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339Nano)
+	body := `[
+		{"name": "jobs", "value": 3},
+		{"name": "jobs", "value": 1, "timestamp": "` + recent + `"},
+		{"name": "jobs", "value": 100, "timestamp": "` + stale + `"},
+		{"name": "errors", "value": 1}
+	]`
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ingest", strings.NewReader(body)))
+	if w.Code != http.StatusNoContent {
+		t.Fatal("Expected ", w.Code, " to equal ", http.StatusNoContent, ": ", w.Body.String())
+	}
+
+	if val := g.Get("jobs").Rate(); val != 4 {
+		t.Error("Expected ", val, " to equal ", 4)
+	}
+	if val := g.Get("jobs").DroppedLate(); val != 100 {
+		t.Error("Expected dropped ", val, " to equal ", 100)
+	}
+	if val := g.Get("errors").Rate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+}
+
+func TestIngestHandler_Invalid(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	h := IngestHandler(g, AllowNames("ok"))
+
+	for _, tc := range []struct {
+		method, body string
+		code         int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "not json", http.StatusBadRequest},
+		{http.MethodPost, `[{"name": "ok", "value": 1}, {"value": 1}]`, http.StatusBadRequest},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tc.method, "/ingest", strings.NewReader(tc.body)))
+		if w.Code != tc.code {
+			t.Error("Expected ", w.Code, " to equal ", tc.code, " for ", tc.body)
+		}
+	}
+
+	// Nothing from a rejected batch is applied
+	if g.Len() != 0 {
+		t.Error("Expected ", g.Len(), " counters to equal ", 0)
+	}
+}
+
+func TestIngestHandler_UnknownNames(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	g.Get("known")
+	post := func(h http.Handler, body string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ingest", strings.NewReader(body)))
+		return w.Code
+	}
+
+	// By default only counters already in the registry can be fed
+	h := IngestHandler(g)
+	if code := post(h, `[{"name": "known", "value": 1}]`); code != http.StatusNoContent {
+		t.Error("Expected ", code, " to equal ", http.StatusNoContent)
+	}
+	if code := post(h, `[{"name": "known", "value": 1}, {"name": "new", "value": 1}]`); code != http.StatusBadRequest {
+		t.Error("Expected ", code, " to equal ", http.StatusBadRequest)
+	}
+
+	// MaxCounters admits new names until the registry is full
+	h = IngestHandler(g, MaxCounters(3))
+	if code := post(h, `[{"name": "a", "value": 1}, {"name": "b", "value": 1}]`); code != http.StatusNoContent {
+		t.Error("Expected ", code, " to equal ", http.StatusNoContent)
+	}
+	if code := post(h, `[{"name": "c", "value": 1}]`); code != http.StatusBadRequest {
+		t.Error("Expected ", code, " to equal ", http.StatusBadRequest)
+	}
+
+	if g.Len() != 3 {
+		t.Error("Expected ", g.Len(), " counters to equal ", 3)
+	}
+	if val := g.Get("known").Rate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+}
@@ -0,0 +1,230 @@
+package ratecounterhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+// A KeyFunc names the registry counter a request is counted in
+type KeyFunc func(r *http.Request) string
+
+// MethodPath is the default KeyFunc, counting requests by method and path,
+// e.g. "GET /users". Services with IDs in their paths should pass a KeyFunc
+// which maps them onto routes, to keep the number of counters bounded.
+func MethodPath(r *http.Request) string {
+	return r.Method + " " + r.URL.Path
+}
+
+// Middleware returns an http.Handler which counts each request in the
+// registry counter named by key, MethodPath if nil, before passing it to
+// next:
+//
+//	http.ListenAndServe(":8080", ratecounterhttp.Middleware(g, nil, mux))
+//
+// Once next returns the request is also counted by the class of its
+// status, in a counter named after the key and the class, such as
+// "GET /users 2xx" or "GET /users 5xx". See ErrorRatio.
+func Middleware(g *ratecounter.Registry, key KeyFunc, next http.Handler) http.Handler {
+	if key == nil {
+		key = MethodPath
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := key(r)
+		g.Get(name).Incr(1)
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		g.Get(name + " " + StatusClass(sw.status())).Incr(1)
+	})
+}
+
+// StatusClass returns the class of an HTTP status code, "1xx" to "5xx"
+func StatusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "other"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
+
+// ErrorRatio returns the share, between 0 and 1, of the requests Middleware
+// counted under key in the last interval which got a 5xx response. It
+// returns 0 if there were none, and creates no counters.
+func ErrorRatio(g *ratecounter.Registry, key string) float64 {
+	total, ok := g.Lookup(key)
+	if !ok {
+		return 0
+	}
+	failed, ok := g.Lookup(key + " 5xx")
+	if !ok {
+		return 0
+	}
+	return ratecounter.Ratio(failed, total)
+}
+
+// A statusWriter records the status code written through it
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes through to the underlying writer, if it supports
+// them
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.code == 0 {
+			w.code = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// status returns the code written, 200 if the handler wrote nothing
+func (w *statusWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// taggedRates is the body RatesHandler writes when asked for tags
+type taggedRates struct {
+	Tags  map[string]string `json:"tags"`
+	Rates map[string]int64  `json:"rates"`
+}
+
+// RatesHandler returns an http.Handler which reports the rate of every
+// counter in the registry as a JSON object, all read at the same instant:
+//
+//	{"GET /users": 12, "POST /orders": 3}
+//
+// With a tags query parameter, e.g. /rates?tags, the rates are wrapped
+// with the tags the registry's counters carry:
+//
+//	{"tags": {"service": "api"}, "rates": {"GET /users": 12}}
+func RatesHandler(g *ratecounter.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		snap := g.ConsistentSnapshot()
+		rates := make(map[string]int64, len(snap.Counters))
+		for name, s := range snap.Counters {
+			rates[name] = s.Rate
+		}
+
+		var body interface{} = rates
+		if _, ok := r.URL.Query()["tags"]; ok {
+			body = taggedRates{Tags: g.Tags(), Rates: rates}
+		}
+
+		writeJSON(w, body)
+	})
+}
+
+// bucketDelta is the part of a counter's buckets BucketsHandler writes
+type bucketDelta struct {
+	// When the first bucket began, in milliseconds since the Unix epoch
+	Start int64 `json:"start"`
+	// How long each bucket covers, in milliseconds
+	Width   int64   `json:"width"`
+	Buckets []int64 `json:"buckets"`
+}
+
+// bucketsBody is the body BucketsHandler writes
+type bucketsBody struct {
+	// The since to pass on the next request
+	Cursor   int64                  `json:"cursor"`
+	Counters map[string]bucketDelta `json:"counters"`
+}
+
+// BucketsHandler returns an http.Handler which reports the buckets of every
+// counter in the registry, all read at the same instant, with a cursor:
+//
+//	{"cursor": 1539000000500, "counters": {"GET /users": {"start": 1539000000000, "width": 250, "buckets": [4, 1]}}}
+//
+// Passing the cursor back as the since query parameter, e.g.
+// /buckets?since=1539000000500, returns only the buckets which could have
+// changed since then: the one the cursor fell in and those after it. A
+// scraper polling faster than the interval transfers a bucket or two per
+// counter rather than the whole window, and merges them by start time. A
+// counter missing from the response has been removed. Events IncrAt
+// records in buckets ending before the cursor are not sent again.
+func BucketsHandler(g *ratecounter.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var since int64
+		if v := r.URL.Query().Get("since"); v != "" {
+			var err error
+			if since, err = strconv.ParseInt(v, 10, 64); err != nil {
+				http.Error(w, "invalid since", http.StatusBadRequest)
+				return
+			}
+		}
+
+		snap := g.ConsistentSnapshot()
+		body := bucketsBody{
+			Cursor:   snap.At.UnixNano() / int64(time.Millisecond),
+			Counters: make(map[string]bucketDelta, len(snap.Counters)),
+		}
+		for name, s := range snap.Counters {
+			width := int64(s.BucketWidth / time.Millisecond)
+			// Skip the buckets which ended at or before since
+			ii := 0
+			for ii < len(s.Buckets)-1 && s.BucketStart(ii+1).UnixNano()/int64(time.Millisecond) <= since {
+				ii++
+			}
+			body.Counters[name] = bucketDelta{
+				Start:   s.BucketStart(ii).UnixNano() / int64(time.Millisecond),
+				Width:   width,
+				Buckets: s.Buckets[ii:],
+			}
+		}
+
+		writeJSON(w, body)
+	})
+}
+
+// writeJSON writes body to w as JSON, or an error if it cannot be encoded
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	// The client has gone if this fails, so there is no one to tell
+	_, _ = w.Write(append(b, '\n'))
+}
@@ -0,0 +1,225 @@
+package ratecounterhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+func TestMiddleware(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	h := Middleware(g, nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	for _, target := range []string{"/users", "/users", "/orders"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusTeapot {
+			t.Error("Expected ", w.Code, " to equal ", http.StatusTeapot)
+		}
+	}
+
+	if val := g.Get("GET /users").Rate(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+	if val := g.Get("GET /orders").Rate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+}
+
+func TestMiddleware_KeyFunc(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	route := func(r *http.Request) string {
+		if strings.HasPrefix(r.URL.Path, "/users/") {
+			return "/users/:id"
+		}
+		return r.URL.Path
+	}
+	h := Middleware(g, route, http.NotFoundHandler())
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/2", nil))
+
+	// The route and its 4xx class
+	if g.Len() != 2 {
+		t.Error("Expected ", g.Len(), " to equal ", 2)
+	}
+	if val := g.Get("/users/:id").Rate(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+}
+
+func TestRatesHandler(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	g.Get("GET /users").Incr(12)
+	g.Get("POST /orders").Incr(3)
+
+	w := httptest.NewRecorder()
+	RatesHandler(g).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rates", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Error("Expected ", ct, " to equal ", "application/json")
+	}
+
+	var rates map[string]int64
+	if err := json.Unmarshal(w.Body.Bytes(), &rates); err != nil {
+		t.Fatal(err)
+	}
+	if rates["GET /users"] != 12 || rates["POST /orders"] != 3 || len(rates) != 2 {
+		t.Error("Expected ", rates, " to equal ", map[string]int64{"GET /users": 12, "POST /orders": 3})
+	}
+
+	w = httptest.NewRecorder()
+	RatesHandler(g).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/rates", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Error("Expected ", w.Code, " to equal ", http.StatusMethodNotAllowed)
+	}
+}
+
+func TestRatesHandler_Tags(t *testing.T) {
+	g := ratecounter.NewRegistry(1*time.Minute, ratecounter.WithTags(map[string]string{"service": "api"}))
+	g.Get("GET /users").Incr(12)
+
+	w := httptest.NewRecorder()
+	RatesHandler(g).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rates?tags", nil))
+
+	var body struct {
+		Tags  map[string]string
+		Rates map[string]int64
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Tags["service"] != "api" {
+		t.Error("Expected ", body.Tags, " to equal ", map[string]string{"service": "api"})
+	}
+	if body.Rates["GET /users"] != 12 {
+		t.Error("Expected ", body.Rates, " to equal ", map[string]int64{"GET /users": 12})
+	}
+}
+
+func TestMiddleware_StatusClasses(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	h := Middleware(g, nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/moved":
+			http.Redirect(w, r, "/", http.StatusFound)
+		default:
+			// Writing the body alone sends a 200
+			w.Write([]byte("ok"))
+		}
+	}))
+
+	for _, target := range []string{"/", "/", "/missing", "/broken", "/moved"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	for name, expected := range map[string]int64{
+		"GET / 2xx":        2,
+		"GET /missing 4xx": 1,
+		"GET /broken 5xx":  1,
+		"GET /moved 3xx":   1,
+	} {
+		if val := g.Get(name).Rate(); val != expected {
+			t.Error("Expected ", name, " ", val, " to equal ", expected)
+		}
+	}
+
+	if val := ErrorRatio(g, "GET /broken"); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+	if val := ErrorRatio(g, "GET /"); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+
+	// Reading the ratio of an unseen key creates nothing
+	before := g.Len()
+	if val := ErrorRatio(g, "GET /never"); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+	if g.Len() != before {
+		t.Error("Expected ", g.Len(), " to equal ", before)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	for code, expected := range map[int]string{100: "1xx", 204: "2xx", 302: "3xx", 404: "4xx", 503: "5xx", 0: "other", 600: "other"} {
+		if val := StatusClass(code); val != expected {
+			t.Error("Expected ", val, " to equal ", expected)
+		}
+	}
+}
+
+// stepClock is a ratecounter.Clock which only moves when told to
+type stepClock struct {
+	now time.Time
+}
+
+func (c *stepClock) Now() time.Time {
+	return c.now
+}
+
+func TestBucketsHandler(t *testing.T) {
+	clock := &stepClock{now: time.Unix(1539000000, 0)}
+	g := ratecounter.NewRegistry(1*time.Second, ratecounter.WithResolution(4), ratecounter.WithClock(clock))
+	g.Get("GET /users").Incr(4)
+
+	get := func(target string) bucketsBody {
+		w := httptest.NewRecorder()
+		BucketsHandler(g).ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatal("Expected ", w.Code, " to equal ", http.StatusOK)
+		}
+		var body bucketsBody
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+
+	// Without a cursor, the whole window
+	body := get("/buckets")
+	users := body.Counters["GET /users"]
+	if len(users.Buckets) != 4 || users.Buckets[3] != 4 || users.Width != 250 {
+		t.Error("Expected ", users, " to hold 4 buckets ending in 4")
+	}
+
+	// With one, the bucket it fell in and those after it
+	clock.now = clock.now.Add(300 * time.Millisecond)
+	g.Get("GET /users").Incr(1)
+	body = get("/buckets?since=" + strconv.FormatInt(body.Cursor, 10))
+	users = body.Counters["GET /users"]
+	if len(users.Buckets) != 2 || users.Buckets[0] != 4 || users.Buckets[1] != 1 {
+		t.Error("Expected ", users.Buckets, " to equal ", []int64{4, 1})
+	}
+	if users.Start != 1539000000000 {
+		t.Error("Expected ", users.Start, " to equal ", 1539000000000)
+	}
+	if body.Cursor != 1539000000300 {
+		t.Error("Expected ", body.Cursor, " to equal ", 1539000000300)
+	}
+
+	w := httptest.NewRecorder()
+	BucketsHandler(g).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/buckets?since=soon", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Error("Expected ", w.Code, " to equal ", http.StatusBadRequest)
+	}
+}
+
+func TestWriteJSON_Error(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeJSON(w, make(chan int))
+	if w.Code != http.StatusInternalServerError {
+		t.Error("Expected ", w.Code, " to equal ", http.StatusInternalServerError)
+	}
+}
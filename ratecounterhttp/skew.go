@@ -0,0 +1,130 @@
+package ratecounterhttp
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// A SkewTracker estimates how far each ingest source's clock is from this
+// process's, so one misconfigured sender can be spotted, and optionally
+// corrected, before it pollutes the window for everyone. Pass one to
+// IngestHandlerWithSkew.
+//
+// The skew is estimated from the newest timestamp in each batch, which is
+// the closest to when the batch was sent, as a moving average. Delivery
+// latency therefore shows up as a small negative skew.
+//
+// Sources are named by their senders, so at most MaxSources are tracked;
+// the one seen least recently is forgotten to make room for a new one. The
+// zero value is ready to use.
+type SkewTracker struct {
+	// Correct shifts every timestamp from a source by its estimated skew
+	// before it is applied
+	Correct bool
+	// Alpha is the weight of the newest batch in the moving average,
+	// DefaultSkewAlpha if zero
+	Alpha float64
+	// MaxSources is the most sources tracked at once, DefaultMaxSkewSources
+	// if zero
+	MaxSources int
+
+	mu sync.Mutex
+	// Each source's element in lru
+	skews map[string]*list.Element
+	// The sources, most recently seen first
+	lru *list.List
+	now func() time.Time
+}
+
+// A skewEntry is one source's estimate, held in a SkewTracker's lru
+type skewEntry struct {
+	source string
+	skew   float64
+}
+
+// DefaultSkewAlpha is the SkewTracker Alpha used when none is set
+const DefaultSkewAlpha = 0.2
+
+// DefaultMaxSkewSources is the SkewTracker MaxSources used when none is set
+const DefaultMaxSkewSources = 1024
+
+// NewSkewTracker Constructs a new SkewTracker which corrects timestamps if
+// correct is set
+func NewSkewTracker(correct bool) *SkewTracker {
+	return &SkewTracker{Correct: correct}
+}
+
+// Observe records that a batch from source had newest as its newest
+// timestamp, and returns the source's updated skew
+func (s *SkewTracker) Observe(source string, newest time.Time) time.Duration {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	offset := float64(newest.Sub(now()))
+
+	alpha := s.Alpha
+	if alpha == 0 {
+		alpha = DefaultSkewAlpha
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.skews[source]; ok {
+		entry := e.Value.(*skewEntry)
+		entry.skew += alpha * (offset - entry.skew)
+		s.lru.MoveToFront(e)
+		return time.Duration(entry.skew)
+	}
+
+	if s.skews == nil {
+		s.skews = map[string]*list.Element{}
+		s.lru = list.New()
+	}
+	max := s.MaxSources
+	if max <= 0 {
+		max = DefaultMaxSkewSources
+	}
+	for s.lru.Len() >= max {
+		oldest := s.lru.Back()
+		delete(s.skews, oldest.Value.(*skewEntry).source)
+		s.lru.Remove(oldest)
+	}
+	s.skews[source] = s.lru.PushFront(&skewEntry{source: source, skew: offset})
+
+	return time.Duration(offset)
+}
+
+// Skew returns how far ahead source's clock is estimated to be, negative if
+// it is behind, or zero for a source which has not been seen
+func (s *SkewTracker) Skew(source string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.skews[source]; ok {
+		return time.Duration(e.Value.(*skewEntry).skew)
+	}
+	return 0
+}
+
+// Skews returns the estimated skew of every source tracked
+func (s *SkewTracker) Skews() map[string]time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	skews := make(map[string]time.Duration, len(s.skews))
+	for source, e := range s.skews {
+		skews[source] = time.Duration(e.Value.(*skewEntry).skew)
+	}
+	return skews
+}
+
+// correct returns t as it would read on this process's clock
+func (s *SkewTracker) correct(t time.Time, skew time.Duration) time.Time {
+	if !s.Correct {
+		return t
+	}
+	return t.Add(-skew)
+}
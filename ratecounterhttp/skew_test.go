@@ -0,0 +1,100 @@
+package ratecounterhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+func TestSkewTracker_Observe(t *testing.T) {
+	now := time.Date(2018, 10, 8, 12, 0, 0, 0, time.UTC)
+	s := NewSkewTracker(false)
+	s.now = func() time.Time { return now }
+
+	if skew := s.Observe("a", now.Add(10*time.Second)); skew != 10*time.Second {
+		t.Error("Expected ", skew, " to equal ", 10*time.Second)
+	}
+	// The estimate moves a fifth of the way towards each new offset
+	if skew := s.Observe("a", now); skew != 8*time.Second {
+		t.Error("Expected ", skew, " to equal ", 8*time.Second)
+	}
+
+	if skew := s.Skew("b"); skew != 0 {
+		t.Error("Expected unseen source skew ", skew, " to equal ", 0)
+	}
+	if skews := s.Skews(); len(skews) != 1 || skews["a"] != 8*time.Second {
+		t.Error("Expected ", skews, " to only hold a")
+	}
+}
+
+func TestIngestHandlerWithSkew(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	s := NewSkewTracker(true)
+	h := IngestHandlerWithSkew(g, s, AllowNames("jobs"))
+
+	// This sender's clock is two hours ahead, so without correction its
+	// events would all be credited as happening now
+	ahead := time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339Nano)
+	body := `[{"name": "jobs", "value": 2, "timestamp": "` + ahead + `", "source": "fast"}]`
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ingest", strings.NewReader(body)))
+	if w.Code != http.StatusNoContent {
+		t.Fatal("Expected ", w.Code, " to equal ", http.StatusNoContent, ": ", w.Body.String())
+	}
+
+	if skew := s.Skew("fast"); skew < 119*time.Minute || skew > 121*time.Minute {
+		t.Error("Expected ", skew, " to be about ", 2*time.Hour)
+	}
+	if val := g.Get("jobs").Rate(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+
+	// Sources default to the remote host
+	w = httptest.NewRecorder()
+	body = `[{"name": "jobs", "value": 1, "timestamp": "` + time.Now().UTC().Format(time.RFC3339Nano) + `"}]`
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ingest", strings.NewReader(body)))
+	if _, ok := s.Skews()["192.0.2.1"]; !ok {
+		t.Error("Expected a skew for the remote host, got", s.Skews())
+	}
+}
+
+func TestSkewTracker_ZeroValue(t *testing.T) {
+	var s SkewTracker
+
+	if skew := s.Skew("a"); skew != 0 {
+		t.Error("Expected ", skew, " to equal ", 0)
+	}
+	if skew := s.Observe("a", time.Now().Add(time.Hour)); skew < 59*time.Minute {
+		t.Error("Expected ", skew, " to be about ", time.Hour)
+	}
+	if len(s.Skews()) != 1 {
+		t.Error("Expected ", s.Skews(), " to hold one source")
+	}
+}
+
+func TestSkewTracker_MaxSources(t *testing.T) {
+	now := time.Date(2018, 10, 8, 12, 0, 0, 0, time.UTC)
+	s := &SkewTracker{MaxSources: 2, now: func() time.Time { return now }}
+
+	s.Observe("a", now.Add(time.Second))
+	s.Observe("b", now.Add(2*time.Second))
+	s.Observe("a", now.Add(time.Second))
+	// b was seen least recently, so makes room
+	s.Observe("c", now.Add(3*time.Second))
+
+	skews := s.Skews()
+	if len(skews) != 2 {
+		t.Error("Expected ", len(skews), " to equal ", 2)
+	}
+	if _, ok := skews["b"]; ok {
+		t.Error("Expected b to be forgotten, got ", skews)
+	}
+	if skew := s.Skew("c"); skew != 3*time.Second {
+		t.Error("Expected ", skew, " to equal ", 3*time.Second)
+	}
+}
@@ -0,0 +1,156 @@
+package ratecounterhttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+// A Standby mirrors the counters of an active process into a local
+// registry, so if the standby takes over its rates carry on rather than
+// starting from zero. It polls the active process's BucketsHandler, which
+// sends only the buckets changed since the last poll, and merges in how
+// much each has grown.
+type Standby struct {
+	// URL is where the active process serves BucketsHandler
+	URL string
+	// Client makes the requests, http.DefaultClient if nil
+	Client *http.Client
+	// Registry receives the mirrored counters. Counting in it directly as
+	// well mixes the two.
+	Registry *ratecounter.Registry
+	// OnError, if set, is called with the error of each poll Run makes
+	// which fails
+	OnError func(error)
+
+	mu     sync.Mutex
+	cursor int64
+	// The value last seen for each counter's buckets, by start time
+	seen map[string]map[int64]int64
+}
+
+// NewStandby returns a Standby mirroring the BucketsHandler at url into g
+func NewStandby(url string, g *ratecounter.Registry) *Standby {
+	return &Standby{URL: url, Registry: g}
+}
+
+// Poll fetches the buckets changed since the last poll and merges them into
+// the registry
+func (s *Standby) Poll(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return err
+	}
+	if s.cursor != 0 {
+		q := u.Query()
+		q.Set("since", strconv.FormatInt(s.cursor, 10))
+		u.RawQuery = q.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ratecounterhttp: unexpected status %s from %s", resp.Status, s.URL)
+	}
+
+	var body bucketsBody
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+	s.apply(body)
+	return nil
+}
+
+// apply merges the growth of each bucket in body since it was last seen
+func (s *Standby) apply(body bucketsBody) {
+	if s.seen == nil {
+		s.seen = map[string]map[int64]int64{}
+	}
+
+	for name, d := range body.Counters {
+		seen := s.seen[name]
+		if seen == nil {
+			seen = map[int64]int64{}
+			s.seen[name] = seen
+		}
+		// Buckets before the first sent will not change again
+		for start := range seen {
+			if start < d.Start {
+				delete(seen, start)
+			}
+		}
+
+		growth := make([]int64, len(d.Buckets))
+		for ii, val := range d.Buckets {
+			start := d.Start + int64(ii)*d.Width
+			growth[ii] = val - seen[start]
+			seen[start] = val
+		}
+		s.Registry.Get(name).Merge(ratecounter.Snapshot{
+			Buckets:     growth,
+			Start:       time.Unix(0, d.Start*int64(time.Millisecond)),
+			BucketWidth: time.Duration(d.Width) * time.Millisecond,
+		})
+	}
+	for name := range s.seen {
+		if _, ok := body.Counters[name]; !ok {
+			delete(s.seen, name)
+		}
+	}
+	s.cursor = body.Cursor
+}
+
+// Run polls every period until ctx is done, returning ctx.Err(), or the
+// registry is closed, returning nil. Polls which fail are passed to OnError
+// and retried on the next tick.
+func (s *Standby) Run(ctx context.Context, every time.Duration) error {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer s.Registry.OnClose(cancelCloser(cancel))()
+
+	t := time.NewTicker(every)
+	defer t.Stop()
+
+	for {
+		if err := s.Poll(ctx); err != nil && ctx.Err() == nil && s.OnError != nil {
+			s.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return parent.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// cancelCloser is an io.Closer which cancels a context
+type cancelCloser context.CancelFunc
+
+func (c cancelCloser) Close() error {
+	c()
+	return nil
+}
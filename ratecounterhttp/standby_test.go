@@ -0,0 +1,94 @@
+package ratecounterhttp
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+func TestStandby(t *testing.T) {
+	clock := &stepClock{now: time.Unix(1539000000, 0)}
+	active := ratecounter.NewRegistry(1*time.Second, ratecounter.WithResolution(4), ratecounter.WithClock(clock))
+	srv := httptest.NewServer(BucketsHandler(active))
+	defer srv.Close()
+
+	g := ratecounter.NewRegistry(1*time.Second, ratecounter.WithResolution(4), ratecounter.WithClock(clock))
+	s := NewStandby(srv.URL, g)
+
+	active.Get("GET /users").Incr(4)
+	if err := s.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if val := g.Get("GET /users").Rate(); val != 4 {
+		t.Error("Expected ", val, " to equal ", 4)
+	}
+
+	// A bucket sent again is only credited with what it grew by
+	active.Get("GET /users").Incr(1)
+	clock.now = clock.now.Add(300 * time.Millisecond)
+	active.Get("GET /users").Incr(2)
+	if err := s.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if val, expected := g.Get("GET /users").Rate(), active.Get("GET /users").Rate(); val != expected {
+		t.Error("Expected ", val, " to equal ", expected)
+	}
+	if len(s.seen["GET /users"]) != 2 {
+		t.Error("Expected ", s.seen["GET /users"], " to hold 2 buckets")
+	}
+
+	// The mirror expires with the active counter
+	clock.now = clock.now.Add(time.Second)
+	if err := s.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if val := g.Get("GET /users").Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestStandby_Run(t *testing.T) {
+	active := ratecounter.NewRegistry(1 * time.Minute)
+	active.Get("jobs").Incr(3)
+	srv := httptest.NewServer(BucketsHandler(active))
+	defer srv.Close()
+
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- NewStandby(srv.URL, g).Run(ctx, time.Millisecond) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for g.Get("jobs").Rate() != 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Error("Expected ", err, " to equal ", context.Canceled)
+	}
+	if val := g.Get("jobs").Rate(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+}
+
+func TestStandby_RunRegistryClose(t *testing.T) {
+	srv := httptest.NewServer(BucketsHandler(ratecounter.NewRegistry(1 * time.Minute)))
+	defer srv.Close()
+
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	done := make(chan error)
+	go func() { done <- NewStandby(srv.URL, g).Run(context.Background(), time.Millisecond) }()
+
+	g.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error("Expected Run to return nil once the registry is closed, got", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Run to return with the registry closed")
+	}
+}
@@ -0,0 +1,164 @@
+// Package ratecounterloadgen drives configurable concurrent Incr patterns
+// against any counter and reports the throughput achieved and how far the
+// counter's rate was from what was actually sent, so a configuration can
+// be validated before it goes to production.
+package ratecounterloadgen
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+// A Pattern returns the target number of events per second at elapsed
+// into a run
+type Pattern func(elapsed time.Duration) float64
+
+// Steady sends perSecond events a second throughout
+func Steady(perSecond float64) Pattern {
+	return func(time.Duration) float64 {
+		return perSecond
+	}
+}
+
+// Bursty sends perSecond events a second, rising to burst events a second
+// for length at the start of every period
+func Bursty(perSecond, burst float64, every, length time.Duration) Pattern {
+	return func(elapsed time.Duration) float64 {
+		if elapsed%every < length {
+			return burst
+		}
+		return perSecond
+	}
+}
+
+// Diurnal follows a sine wave around mean events a second, peaking at
+// mean+amplitude a quarter of the way through each period, like daily
+// traffic compressed into period
+func Diurnal(mean, amplitude float64, period time.Duration) Pattern {
+	return func(elapsed time.Duration) float64 {
+		rate := mean + amplitude*math.Sin(2*math.Pi*float64(elapsed)/float64(period))
+		if rate < 0 {
+			return 0
+		}
+		return rate
+	}
+}
+
+// A Config describes one run
+type Config struct {
+	// Pattern is the load to send
+	Pattern Pattern
+	// Duration is how long to run for
+	Duration time.Duration
+	// Interval is the window of the counter under test, used to work out
+	// what its rate should be at the end
+	Interval time.Duration
+	// Workers is how many goroutines call Incr concurrently, 1 if zero
+	Workers int
+	// Tick is how often the pattern is sampled, 10ms if zero
+	Tick time.Duration
+}
+
+// A Result reports how a run went
+type Result struct {
+	// Sent is the number of events sent
+	Sent int64
+	// Elapsed is how long the run took
+	Elapsed time.Duration
+	// Throughput is the events per second achieved
+	Throughput float64
+	// Expected is the number of events sent in the last Interval, and so
+	// the rate the counter should have reported at the end
+	Expected int64
+	// Rate is the rate the counter reported at the end
+	Rate int64
+	// Error is (Rate - Expected) / Expected, or 0 if nothing was expected
+	Error float64
+}
+
+// tick records how many events were sent by a point in the run
+type tick struct {
+	at   time.Time
+	sent int64
+}
+
+// Run sends cfg.Pattern to c and reports the result. It blocks for at least
+// cfg.Duration.
+func Run(c ratecounter.Rater, cfg Config) Result {
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	period := cfg.Tick
+	if period <= 0 {
+		period = 10 * time.Millisecond
+	}
+
+	jobs := make(chan int64)
+	var wg sync.WaitGroup
+	for ii := 0; ii < workers; ii++ {
+		go func() {
+			for n := range jobs {
+				for ; n > 0; n-- {
+					c.Incr(1)
+				}
+				wg.Done()
+			}
+		}()
+	}
+	defer close(jobs)
+
+	var (
+		ticks []tick
+		sent  int64
+		owed  float64
+	)
+
+	start := time.Now()
+	last := start
+	for now := start; now.Sub(start) < cfg.Duration; now = time.Now() {
+		// Send what the pattern asked for since the last tick, carrying
+		// fractions of an event over
+		owed += cfg.Pattern(now.Sub(start)) * now.Sub(last).Seconds()
+		last = now
+		n := int64(owed)
+		owed -= float64(n)
+
+		share := n / int64(workers)
+		wg.Add(workers)
+		for ii := 0; ii < workers; ii++ {
+			if ii == 0 {
+				jobs <- share + n%int64(workers)
+			} else {
+				jobs <- share
+			}
+		}
+		wg.Wait()
+
+		sent += n
+		ticks = append(ticks, tick{at: time.Now(), sent: n})
+		time.Sleep(period)
+	}
+
+	end := time.Now()
+	res := Result{
+		Sent:    sent,
+		Elapsed: end.Sub(start),
+		Rate:    c.Rate(),
+	}
+	res.Throughput = float64(sent) / res.Elapsed.Seconds()
+
+	for _, t := range ticks {
+		if end.Sub(t.at) < cfg.Interval {
+			res.Expected += t.sent
+		}
+	}
+	if res.Expected != 0 {
+		res.Error = float64(res.Rate-res.Expected) / float64(res.Expected)
+	}
+
+	return res
+}
@@ -0,0 +1,60 @@
+package ratecounterloadgen
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+func TestPatterns(t *testing.T) {
+	if val := Steady(50)(time.Hour); val != 50 {
+		t.Error("Expected ", val, " to equal ", 50)
+	}
+
+	bursty := Bursty(10, 100, time.Second, 100*time.Millisecond)
+	if val := bursty(1050 * time.Millisecond); val != 100 {
+		t.Error("Expected ", val, " to equal ", 100)
+	}
+	if val := bursty(1500 * time.Millisecond); val != 10 {
+		t.Error("Expected ", val, " to equal ", 10)
+	}
+
+	diurnal := Diurnal(100, 50, 24*time.Hour)
+	if val := diurnal(6 * time.Hour); math.Abs(val-150) > 1e-9 {
+		t.Error("Expected ", val, " to equal ", 150)
+	}
+	if val := diurnal(18 * time.Hour); math.Abs(val-50) > 1e-9 {
+		t.Error("Expected ", val, " to equal ", 50)
+	}
+	if val := Diurnal(10, 50, time.Hour)(45 * time.Minute); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestRun(t *testing.T) {
+	// With a window longer than the run, every event sent is expected
+	interval := 10 * time.Second
+	c := ratecounter.NewRateCounter(interval)
+
+	res := Run(c, Config{
+		Pattern:  Steady(10000),
+		Duration: 300 * time.Millisecond,
+		Interval: interval,
+		Workers:  4,
+	})
+
+	if res.Sent < 1000 {
+		t.Error("Expected ", res.Sent, " to be near ", 3000)
+	}
+	if res.Expected != res.Sent {
+		t.Error("Expected ", res.Expected, " to equal ", res.Sent)
+	}
+	if res.Rate != res.Sent || res.Error != 0 {
+		t.Error("Expected a rate of ", res.Sent, " with no error, got ", res)
+	}
+	if res.Throughput <= 0 {
+		t.Error("Expected a throughput, got ", res.Throughput)
+	}
+}
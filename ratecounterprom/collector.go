@@ -0,0 +1,219 @@
+// Package ratecounterprom exposes ratecounter rates to Prometheus.
+//
+//	requests := ratecounter.NewRateCounter(time.Minute)
+//	prometheus.MustRegister(ratecounterprom.NewCollector(requests, prometheus.GaugeOpts{
+//		Name: "requests_per_minute",
+//		Help: "Requests in the last minute.",
+//	}))
+package ratecounterprom
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/paulbellamy/ratecounter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// tagged is implemented by counters carrying tags, such as
+// ratecounter.RateCounter
+type tagged interface {
+	Tags() map[string]string
+}
+
+// A counterCollector exposes one counter as a gauge
+type counterCollector struct {
+	desc    *prometheus.Desc
+	counter ratecounter.Rater
+}
+
+// constLabels returns the constant labels given with tags added, their
+// keys sanitized into valid label names
+func constLabels(base prometheus.Labels, tags map[string]string) prometheus.Labels {
+	labels := prometheus.Labels{}
+	for k, v := range base {
+		labels[k] = v
+	}
+	for k, v := range tags {
+		labels[labelName(k)] = v
+	}
+	return labels
+}
+
+// labelName replaces characters which are not valid in a label name
+func labelName(name string) string {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// NewCollector returns a prometheus.Collector exposing the rolling rate of
+// r as a gauge described by opts. The counter's tags, if it has any, are
+// added to opts.ConstLabels, with their keys made into valid label names.
+func NewCollector(r ratecounter.Rater, opts prometheus.GaugeOpts) prometheus.Collector {
+	var tags map[string]string
+	if t, ok := r.(tagged); ok {
+		tags = t.Tags()
+	}
+
+	return &counterCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+			opts.Help, nil, constLabels(opts.ConstLabels, tags)),
+		counter: r,
+	}
+}
+
+func (c *counterCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *counterCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(c.counter.Rate()))
+}
+
+// A registryCollector exposes every counter in a registry as one gauge,
+// labelled by counter name
+type registryCollector struct {
+	desc     *prometheus.Desc
+	registry *ratecounter.Registry
+}
+
+// NewRegistryCollector returns a prometheus.Collector exposing the rolling
+// rate of every counter in g as a gauge described by opts, with the counter
+// name in the label nameLabel. The registry's tags are added to
+// opts.ConstLabels, as NewCollector adds a counter's. Counters removed with
+// Unregister are exposed as zero until their tombstone period ends.
+func NewRegistryCollector(g *ratecounter.Registry, opts prometheus.GaugeOpts, nameLabel string) prometheus.Collector {
+	return &registryCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+			opts.Help, []string{nameLabel}, constLabels(opts.ConstLabels, g.Tags())),
+		registry: g,
+	}
+}
+
+func (c *registryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *registryCollector) Collect(ch chan<- prometheus.Metric) {
+	c.registry.Each(func(name string, r *ratecounter.RateCounter) {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(r.Rate()), name)
+	})
+	for _, name := range c.registry.Tombstones() {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, 0, name)
+	}
+}
+
+// Other is the label value the rates of the counters left out by a bounded
+// registry collector are summed under
+const Other = "other"
+
+// A boundedCollector is a registryCollector exposing at most max counters
+// by name, and the rest summed under Other
+type boundedCollector struct {
+	registryCollector
+	max        int
+	suppressed *prometheus.Desc
+
+	mu sync.Mutex
+	// The names with a series of their own
+	admitted map[string]bool
+}
+
+// NewBoundedRegistryCollector is NewRegistryCollector, but exposes at most
+// maxSeries counters under their own names, so a registry with unbounded
+// keys cannot blow up the scrape. Counters get a series in the order they
+// are first seen and keep it while they are registered; the rest are summed
+// under the label value Other, and how many were left out is exposed as the
+// gauge <name>_suppressed_series. A counter named Other is always summed
+// with the rest.
+func NewBoundedRegistryCollector(g *ratecounter.Registry, opts prometheus.GaugeOpts, nameLabel string, maxSeries int) prometheus.Collector {
+	if maxSeries < 0 {
+		panic("ratecounterprom: maxSeries cannot be negative")
+	}
+
+	labels := constLabels(opts.ConstLabels, g.Tags())
+	return &boundedCollector{
+		registryCollector: registryCollector{
+			desc: prometheus.NewDesc(
+				prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+				opts.Help, []string{nameLabel}, labels),
+			registry: g,
+		},
+		max: maxSeries,
+		suppressed: prometheus.NewDesc(
+			prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name+"_suppressed_series"),
+			"Counters summed under "+nameLabel+"=\""+Other+"\" to bound the number of series.",
+			nil, labels),
+		admitted: map[string]bool{},
+	}
+}
+
+func (c *boundedCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+	ch <- c.suppressed
+}
+
+func (c *boundedCollector) Collect(ch chan<- prometheus.Metric) {
+	rates := map[string]int64{}
+	c.registry.Each(func(name string, r *ratecounter.RateCounter) {
+		rates[name] = r.Rate()
+	})
+	tombstoned := map[string]bool{}
+	for _, name := range c.registry.Tombstones() {
+		tombstoned[name] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Free the series of counters which are gone, then hand them out to
+	// new ones, by name so scrapes are repeatable
+	for name := range c.admitted {
+		if _, ok := rates[name]; !ok && !tombstoned[name] {
+			delete(c.admitted, name)
+		}
+	}
+	var waiting []string
+	for name := range rates {
+		if !c.admitted[name] && name != Other {
+			waiting = append(waiting, name)
+		}
+	}
+	sort.Strings(waiting)
+	for _, name := range waiting {
+		if len(c.admitted) >= c.max {
+			break
+		}
+		c.admitted[name] = true
+	}
+
+	var rest int64
+	var suppressed int
+	for name, rate := range rates {
+		if c.admitted[name] {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(rate), name)
+		} else {
+			rest += rate
+			suppressed++
+		}
+	}
+	for name := range tombstoned {
+		if c.admitted[name] {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, 0, name)
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(rest), Other)
+	ch <- prometheus.MustNewConstMetric(c.suppressed, prometheus.GaugeValue, float64(suppressed))
+}
@@ -0,0 +1,151 @@
+package ratecounterprom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func gather(t *testing.T, c prometheus.Collector) map[string]float64 {
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatal(err)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]float64{}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			key := f.GetName()
+			for _, l := range m.GetLabel() {
+				key += "," + l.GetName() + "=" + l.GetValue()
+			}
+			values[key] = m.GetGauge().GetValue()
+		}
+	}
+	return values
+}
+
+func TestNewCollector(t *testing.T) {
+	r := ratecounter.NewRateCounterWithOptions(1*time.Second,
+		ratecounter.WithTags(map[string]string{"route": "/users"}))
+	r.Incr(3)
+
+	values := gather(t, NewCollector(r, prometheus.GaugeOpts{
+		Namespace:   "app",
+		Name:        "requests",
+		Help:        "Requests in the last second.",
+		ConstLabels: prometheus.Labels{"env": "test"},
+	}))
+
+	if val := values["app_requests,env=test,route=/users"]; val != 3 {
+		t.Error("Expected ", val, " to equal ", 3, " in ", values)
+	}
+}
+
+func TestNewRegistryCollector(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Second)
+	g.Get("/users").Incr(2)
+	g.Get("/orders").Incr(5)
+
+	values := gather(t, NewRegistryCollector(g, prometheus.GaugeOpts{
+		Name: "requests",
+		Help: "Requests in the last second.",
+	}, "route"))
+
+	if val := values["requests,route=/users"]; val != 2 {
+		t.Error("Expected ", val, " to equal ", 2, " in ", values)
+	}
+	if val := values["requests,route=/orders"]; val != 5 {
+		t.Error("Expected ", val, " to equal ", 5, " in ", values)
+	}
+}
+
+func TestNewRegistryCollector_Tags(t *testing.T) {
+	g := ratecounter.NewRegistry(1*time.Second, ratecounter.WithTags(map[string]string{"service-name": "api"}))
+	g.Get("/users").Incr(2)
+
+	// Tag keys which are not valid label names are sanitized rather than
+	// panicking at scrape time
+	values := gather(t, NewRegistryCollector(g, prometheus.GaugeOpts{
+		Name: "requests",
+		Help: "Requests in the last second.",
+	}, "route"))
+	if val := values["requests,route=/users,service_name=api"]; val != 2 {
+		t.Error("Expected ", val, " to equal ", 2, " in ", values)
+	}
+
+	values = gather(t, NewBoundedRegistryCollector(g, prometheus.GaugeOpts{
+		Name: "requests",
+		Help: "Requests in the last second.",
+	}, "route", 1))
+	if val := values["requests,route=/users,service_name=api"]; val != 2 {
+		t.Error("Expected ", val, " to equal ", 2, " in ", values)
+	}
+}
+
+func TestLabelName(t *testing.T) {
+	for name, expected := range map[string]string{"route": "route", "service-name": "service_name", "1st": "_1st", "a:b": "a_b", "": "_"} {
+		if val := labelName(name); val != expected {
+			t.Error("Expected ", val, " to equal ", expected)
+		}
+	}
+}
+
+func TestNewRegistryCollector_Tombstones(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Second)
+	g.Get("/old").Incr(4)
+	g.Unregister("/old", time.Minute)
+
+	values := gather(t, NewRegistryCollector(g, prometheus.GaugeOpts{
+		Name: "requests",
+		Help: "Requests in the last second.",
+	}, "route"))
+
+	val, ok := values["requests,route=/old"]
+	if !ok || val != 0 {
+		t.Error("Expected a final zero for /old in ", values)
+	}
+}
+
+func TestNewBoundedRegistryCollector(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Second)
+	g.Get("/a").Incr(1)
+	g.Get("/b").Incr(2)
+	c := NewBoundedRegistryCollector(g, prometheus.GaugeOpts{
+		Name: "requests",
+		Help: "Requests in the last second.",
+	}, "route", 2)
+
+	values := gather(t, c)
+	if val := values["requests,route=/a"]; val != 1 {
+		t.Error("Expected ", val, " to equal ", 1, " in ", values)
+	}
+	if val, ok := values["requests,route=other"]; !ok || val != 0 {
+		t.Error("Expected an empty other series in ", values)
+	}
+
+	// Counters seen later are summed under other, however busy they are
+	g.Get("/c").Incr(4)
+	g.Get("/d").Incr(8)
+
+	values = gather(t, c)
+	if val := values["requests,route=/b"]; val != 2 {
+		t.Error("Expected ", val, " to equal ", 2, " in ", values)
+	}
+	if _, ok := values["requests,route=/c"]; ok {
+		t.Error("Expected /c to be left out of ", values)
+	}
+	if val := values["requests,route=other"]; val != 12 {
+		t.Error("Expected ", val, " to equal ", 12, " in ", values)
+	}
+	if val := values["requests_suppressed_series"]; val != 2 {
+		t.Error("Expected ", val, " to equal ", 2, " in ", values)
+	}
+}
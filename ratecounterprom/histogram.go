@@ -0,0 +1,51 @@
+package ratecounterprom
+
+import (
+	"github.com/paulbellamy/ratecounter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// A histogramCollector exposes a PercentileCounter as a histogram
+type histogramCollector struct {
+	desc    *prometheus.Desc
+	counter *ratecounter.PercentileCounter
+}
+
+// NewHistogramCollector returns a prometheus.Collector exposing the values p
+// observed in its last interval as a histogram described by opts, with
+// cumulative buckets at p's bounds, +Inf, _sum and _count, so
+// histogram_quantile and existing recording rules work on it. opts.Buckets
+// is ignored; the buckets are p's.
+//
+// The histogram covers a sliding window, so its buckets can fall between
+// scrapes, which rate() and increase() would read as counter resets. Use
+// the series directly, e.g. histogram_quantile(0.99, name_bucket), rather
+// than their rates.
+func NewHistogramCollector(p *ratecounter.PercentileCounter, opts prometheus.HistogramOpts) prometheus.Collector {
+	return &histogramCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+			opts.Help, nil, constLabels(opts.ConstLabels, nil)),
+		counter: p,
+	}
+}
+
+func (c *histogramCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *histogramCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.counter.Snapshot()
+
+	// The last count holds the values above the last bound, which only the
+	// +Inf bucket, the total count, includes
+	buckets := make(map[float64]uint64, len(s.Bounds))
+	var count uint64
+	for ii, bound := range s.Bounds {
+		count += uint64(s.Counts[ii])
+		buckets[bound] = count
+	}
+	count += uint64(s.Counts[len(s.Bounds)])
+
+	ch <- prometheus.MustNewConstHistogram(c.desc, count, s.Sum, buckets)
+}
@@ -0,0 +1,51 @@
+package ratecounterprom
+
+import (
+	"testing"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestNewHistogramCollector(t *testing.T) {
+	p := ratecounter.NewPercentileCounter(1*time.Second, []float64{10, 100})
+	p.Observe(5)
+	p.Observe(50)
+	p.Observe(50)
+	p.Observe(1000)
+
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(NewHistogramCollector(p, prometheus.HistogramOpts{
+		Name: "latency_ms",
+		Help: "Request latency over the last second.",
+	})); err != nil {
+		t.Fatal(err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 1 || families[0].GetName() != "latency_ms" {
+		t.Fatal("Expected one latency_ms family in ", families)
+	}
+
+	h := families[0].GetMetric()[0].GetHistogram()
+	if val := h.GetSampleCount(); val != 4 {
+		t.Error("Expected ", val, " to equal ", 4)
+	}
+	if val := h.GetSampleSum(); val != 1105 {
+		t.Error("Expected ", val, " to equal ", 1105)
+	}
+
+	// Buckets are cumulative, and +Inf is implied by the count
+	expected := map[float64]uint64{10: 1, 100: 3}
+	for _, b := range h.GetBucket() {
+		if val := b.GetCumulativeCount(); val != expected[b.GetUpperBound()] {
+			t.Error("Expected ", val, " to equal ", expected[b.GetUpperBound()], " for bound ", b.GetUpperBound())
+		}
+	}
+	if len(h.GetBucket()) != len(expected) {
+		t.Error("Expected ", len(h.GetBucket()), " to equal ", len(expected))
+	}
+}
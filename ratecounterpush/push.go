@@ -0,0 +1,301 @@
+// Package ratecounterpush sends counter rates to a Prometheus Pushgateway
+// compatible endpoint, so short-lived batch jobs can report their
+// throughput before they exit.
+//
+//	pusher := ratecounterpush.New("http://pushgateway:9091", "nightly_import")
+//	pusher.Final = map[string]ratecounter.Rater{"rows_imported": rows}
+//	defer pusher.Close()
+//
+// A Pusher is an io.Closer, so it can be tied to a registry with
+// Registry.OnClose, pushing Final when the registry is closed.
+package ratecounterpush
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+// ErrNameCollision means two counters' names sanitize to the same metric
+// name, e.g. "a.b" and "a_b", so one would overwrite the other
+var ErrNameCollision = errors.New("ratecounterpush: counter names collide")
+
+// A Pusher sends counter rates to a Pushgateway under a job name
+type Pusher struct {
+	// URL is the base address of the Pushgateway
+	URL string
+	// Job is the job name the metrics are grouped under
+	Job string
+	// Client is used to send requests, http.DefaultClient if nil
+	Client *http.Client
+	// Noise is applied to every rate pushed, if set
+	Noise *ratecounter.Noise
+	// TopN, if positive, limits each push to the TopN counters with the
+	// highest rates, plus one gauge holding the sum of the rest, to keep the
+	// cost bounded for services with very many counters
+	TopN int
+	// Other names the gauge holding the rest when TopN is set, "other" if
+	// empty. If a counter has the same name, underscores are appended until
+	// it is distinct.
+	Other string
+	// Final, if set, is pushed by Close, so a job can report its counters
+	// as it exits with a deferred Close
+	Final map[string]ratecounter.Rater
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// New constructs a new Pusher for the Pushgateway at url
+func New(url, job string) *Pusher {
+	return &Pusher{URL: url, Job: job}
+}
+
+// Push replaces the metrics grouped under the job with the current rate of
+// each counter, exposed as a gauge named after its map key. It returns
+// ratecounter.ErrClosed after Close, and ErrNameCollision, pushing nothing,
+// if two keys sanitize to the same metric name.
+func (p *Pusher) Push(counters map[string]ratecounter.Rater) error {
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		return ratecounter.ErrClosed
+	}
+
+	return p.push(counters)
+}
+
+// Close pushes Final, if it is set, and closes the pusher. Later calls do
+// nothing and return nil.
+func (p *Pusher) Close() error {
+	p.mu.Lock()
+	closed := p.closed
+	p.closed = true
+	p.mu.Unlock()
+
+	if closed || p.Final == nil {
+		return nil
+	}
+	return p.push(p.Final)
+}
+
+// CloseWhenDone calls Close once ctx is done, pushing Final, so a job can
+// tie its final report to its own shutdown. The error from that push is
+// lost; call Close directly to see it. The returned function unbinds the
+// pusher from ctx, reporting false if Close has already been called for it.
+func (p *Pusher) CloseWhenDone(ctx context.Context) (stop func() bool) {
+	return context.AfterFunc(ctx, func() { p.Close() })
+}
+
+// push is Push, whether or not the pusher is closed
+func (p *Pusher) push(counters map[string]ratecounter.Rater) error {
+	var body bytes.Buffer
+	if err := p.write(&body, counters); err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimSuffix(p.URL, "/") + "/metrics/job/" + url.PathEscape(p.Job)
+	req, err := http.NewRequest(http.MethodPut, endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused; the status is what
+	// matters, so a failure here is not worth reporting
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("ratecounterpush: unexpected status %s from %s", resp.Status, endpoint)
+	}
+
+	return nil
+}
+
+// Write writes the current rate of each counter in the Prometheus text
+// exposition format, sorted by name. It returns ErrNameCollision, writing
+// nothing, if two names sanitize to the same metric name.
+func Write(w io.Writer, counters map[string]ratecounter.Rater) error {
+	return (&Pusher{}).write(w, counters)
+}
+
+// write is Write, applying the pusher's Noise and TopN
+func (p *Pusher) write(w io.Writer, counters map[string]ratecounter.Rater) error {
+	if err := collisions(counters); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(counters))
+	rates := make(map[string]int64, len(counters))
+	for name, r := range counters {
+		names = append(names, name)
+		rates[name] = r.Rate()
+		if p.Noise != nil {
+			rates[name] = p.Noise.Apply(rates[name])
+		}
+	}
+
+	var rest int64
+	if p.TopN > 0 && len(names) > p.TopN {
+		// Highest first, by name among equals so pushes are stable
+		sort.Slice(names, func(i, j int) bool {
+			if rates[names[i]] != rates[names[j]] {
+				return rates[names[i]] > rates[names[j]]
+			}
+			return names[i] < names[j]
+		})
+		for _, name := range names[p.TopN:] {
+			rest += rates[name]
+		}
+		names = names[:p.TopN]
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		metric := sanitize(name)
+		if d, ok := counters[name].(described); ok && d.Description() != "" {
+			if _, err := fmt.Fprintf(w, "# HELP %s %s\n", metric, help(d)); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "# TYPE %s gauge\n%s%s %d\n", metric, metric, labels(counters[name]), rates[name]); err != nil {
+			return err
+		}
+	}
+
+	if p.TopN > 0 && len(counters) > p.TopN {
+		other := p.Other
+		if other == "" {
+			other = "other"
+		}
+		metric := sanitize(other)
+		for sanitizesTo(counters, metric) {
+			metric += "_"
+		}
+		if _, err := fmt.Fprintf(w, "# TYPE %s gauge\n%s %d\n", metric, metric, rest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// collisions returns an error wrapping ErrNameCollision if any two of the
+// counters' names sanitize to the same metric name
+func collisions(counters map[string]ratecounter.Rater) error {
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+	// Sorted so the error names the same pair each time
+	sort.Strings(names)
+
+	seen := make(map[string]string, len(names))
+	for _, name := range names {
+		metric := sanitize(name)
+		if other, ok := seen[metric]; ok {
+			return fmt.Errorf("%w: %q and %q are both %q", ErrNameCollision, other, name, metric)
+		}
+		seen[metric] = name
+	}
+	return nil
+}
+
+// sanitizesTo reports whether any of the counters' names sanitizes to
+// metric
+func sanitizesTo(counters map[string]ratecounter.Rater, metric string) bool {
+	for name := range counters {
+		if sanitize(name) == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// described is implemented by counters carrying metadata, such as
+// ratecounter.RateCounter
+type described interface {
+	Unit() string
+	Description() string
+}
+
+// help returns the HELP text for a counter, escaped for the text format
+func help(d described) string {
+	text := d.Description()
+	if d.Unit() != "" {
+		text += " (" + d.Unit() + ")"
+	}
+
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(text)
+}
+
+// tagged is implemented by counters carrying tags, such as
+// ratecounter.RateCounter
+type tagged interface {
+	Tags() map[string]string
+}
+
+// labels returns a counter's tags formatted as a label set, sorted by name
+func labels(r ratecounter.Rater) string {
+	t, ok := r.(tagged)
+	if !ok {
+		return ""
+	}
+	tags := t.Tags()
+	if len(tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	escape := strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\"", "\\\"")
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		// Unlike metric names, label names cannot contain colons
+		pairs[i] = strings.Replace(sanitize(k), ":", "_", -1) + "=\"" + escape.Replace(tags[k]) + "\""
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// sanitize replaces characters which are not valid in a metric name
+func sanitize(name string) string {
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			return r
+		case r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,280 @@
+package ratecounterpush
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+func TestWrite(t *testing.T) {
+	rows := ratecounter.NewRateCounter(1 * time.Second)
+	rows.Incr(42)
+
+	var buf bytes.Buffer
+	err := Write(&buf, map[string]ratecounter.Rater{
+		"rows.imported": rows,
+		"errors":        ratecounter.NopRateCounter{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "# TYPE errors gauge\nerrors 0\n# TYPE rows_imported gauge\nrows_imported 42\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q to equal %q", buf.String(), expected)
+	}
+}
+
+func TestPusher_Push(t *testing.T) {
+	var method, path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.EscapedPath()
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer srv.Close()
+
+	rows := ratecounter.NewRateCounter(1 * time.Second)
+	rows.Incr(3)
+
+	err := New(srv.URL+"/", "nightly import").Push(map[string]ratecounter.Rater{"rows": rows})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if method != http.MethodPut {
+		t.Error("Expected ", method, " to equal ", http.MethodPut)
+	}
+	if path != "/metrics/job/nightly%20import" {
+		t.Error("Expected ", path, " to equal ", "/metrics/job/nightly%20import")
+	}
+	if body != "# TYPE rows gauge\nrows 3\n" {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
+
+func TestPusher_Push_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	if err := New(srv.URL, "job").Push(nil); err == nil {
+		t.Error("Expected an error for a non-2xx response")
+	}
+}
+
+func TestWrite_NameCollision(t *testing.T) {
+	counters := map[string]ratecounter.Rater{
+		"a.b": ratecounter.NewRateCounter(1 * time.Second),
+		"a_b": ratecounter.NewRateCounter(1 * time.Second),
+	}
+
+	var buf bytes.Buffer
+	err := Write(&buf, counters)
+	if !errors.Is(err, ErrNameCollision) {
+		t.Error("Expected ", err, " to be ", ErrNameCollision)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing written, got %q", buf.String())
+	}
+}
+
+func TestWrite_Help(t *testing.T) {
+	rows := ratecounter.NewRateCounterWithOptions(1*time.Second,
+		ratecounter.WithUnit("rows"), ratecounter.WithDescription("Rows imported"))
+
+	var buf bytes.Buffer
+	if err := Write(&buf, map[string]ratecounter.Rater{"rows": rows}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "# HELP rows Rows imported (rows)\n# TYPE rows gauge\nrows 0\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q to equal %q", buf.String(), expected)
+	}
+}
+
+func TestWrite_Tags(t *testing.T) {
+	requests := ratecounter.NewRateCounterWithOptions(1*time.Second,
+		ratecounter.WithTags(map[string]string{"route": "/users", "method": "GET", "note": `say "hi"`}))
+	requests.Incr(2)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, map[string]ratecounter.Rater{"requests": requests}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "# TYPE requests gauge\nrequests{method=\"GET\",note=\"say \\\"hi\\\"\",route=\"/users\"} 2\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q to equal %q", buf.String(), expected)
+	}
+}
+
+func TestPusher_Push_Noise(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer srv.Close()
+
+	rows := ratecounter.NewRateCounter(1 * time.Second)
+	rows.Incr(42)
+
+	p := New(srv.URL, "job")
+	p.Noise = &ratecounter.Noise{Round: 10}
+	if err := p.Push(map[string]ratecounter.Rater{"rows": rows}); err != nil {
+		t.Fatal(err)
+	}
+
+	if body != "# TYPE rows gauge\nrows 40\n" {
+		t.Errorf("Unexpected body %q", body)
+	}
+	// The counter keeps its exact value
+	if val := rows.Rate(); val != 42 {
+		t.Error("Expected ", val, " to equal ", 42)
+	}
+}
+
+func TestPusher_Write_TopN(t *testing.T) {
+	counters := map[string]ratecounter.Rater{}
+	for name, val := range map[string]int64{"a": 5, "b": 50, "c": 1, "d": 20} {
+		r := ratecounter.NewRateCounter(1 * time.Second)
+		r.Incr(val)
+		counters[name] = r
+	}
+
+	var buf bytes.Buffer
+	p := &Pusher{TopN: 2, Other: "remaining"}
+	if err := p.write(&buf, counters); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "# TYPE b gauge\nb 50\n# TYPE d gauge\nd 20\n# TYPE remaining gauge\nremaining 6\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q to equal %q", buf.String(), expected)
+	}
+
+	// Under the limit everything is sent as usual
+	buf.Reset()
+	p = &Pusher{TopN: 4}
+	if err := p.write(&buf, counters); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("other")) {
+		t.Errorf("Unexpected remainder in %q", buf.String())
+	}
+}
+
+func TestPusher_Write_TopN_OtherCollision(t *testing.T) {
+	counters := map[string]ratecounter.Rater{}
+	for name, val := range map[string]int64{"other": 50, "b": 5, "c": 1} {
+		r := ratecounter.NewRateCounter(1 * time.Second)
+		r.Incr(val)
+		counters[name] = r
+	}
+
+	// The remainder does not reuse the name of a counter it is sent with
+	var buf bytes.Buffer
+	p := &Pusher{TopN: 1}
+	if err := p.write(&buf, counters); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "# TYPE other gauge\nother 50\n# TYPE other_ gauge\nother_ 6\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q to equal %q", buf.String(), expected)
+	}
+}
+
+func TestPusher_Close(t *testing.T) {
+	var pushes int
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushes++
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer srv.Close()
+
+	rows := ratecounter.NewRateCounter(1 * time.Second)
+	rows.Incr(3)
+
+	p := New(srv.URL, "import")
+	p.Final = map[string]ratecounter.Rater{"rows": rows}
+	for i := 0; i < 2; i++ {
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The final counters are flushed once
+	if pushes != 1 {
+		t.Error("Expected ", pushes, " pushes to equal ", 1)
+	}
+	if body != "# TYPE rows gauge\nrows 3\n" {
+		t.Errorf("Unexpected body %q", body)
+	}
+
+	if err := p.Push(p.Final); err != ratecounter.ErrClosed {
+		t.Error("Expected ", err, " to equal ", ratecounter.ErrClosed)
+	}
+}
+
+func TestPusher_RegistryClose(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer srv.Close()
+
+	g := ratecounter.NewRegistry(1 * time.Second)
+	g.Get("rows").Incr(3)
+
+	p := New(srv.URL, "import")
+	p.Final = map[string]ratecounter.Rater{"rows": g.Get("rows")}
+	g.OnClose(p)
+
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if body != "# TYPE rows gauge\nrows 3\n" {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
+
+func TestPusher_CloseWhenDone(t *testing.T) {
+	pushed := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		pushed <- string(b)
+	}))
+	defer srv.Close()
+
+	rows := ratecounter.NewRateCounter(1 * time.Second)
+	rows.Incr(3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(srv.URL, "import")
+	p.Final = map[string]ratecounter.Rater{"rows": rows}
+	p.CloseWhenDone(ctx)
+
+	cancel()
+	select {
+	case body := <-pushed:
+		if body != "# TYPE rows gauge\nrows 3\n" {
+			t.Errorf("Unexpected body %q", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the final counters to be pushed with the context")
+	}
+}
@@ -0,0 +1,198 @@
+// Package ratecounterudp increments ratecounter Registry counters from UDP
+// datagrams, for extremely cheap instrumentation from shell scripts and cron
+// jobs on the same host:
+//
+//	echo "backups 1" | nc -u -w0 127.0.0.1 8125
+//
+// Each datagram holds one or more lines of either "name value" or the
+// statsd counter form "name:value|c". The value may be omitted and
+// defaults to 1. Malformed lines are ignored.
+//
+// Datagrams are easily spoofed, so senders are not trusted to create
+// counters: a line naming a counter which is not already in the registry
+// is ignored unless AllowNames, AllowFunc or MaxCounters admits it.
+package ratecounterudp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+// MaxDatagramSize is the largest datagram read; longer ones are truncated
+const MaxDatagramSize = 65535
+
+// An Option configures which counters Serve and Apply may create
+type Option func(*config)
+
+type config struct {
+	allow       func(name string) bool
+	maxCounters int
+}
+
+// AllowNames lets the counters named be created, as well as incrementing
+// those already in the registry
+func AllowNames(names ...string) Option {
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+	return AllowFunc(func(name string) bool { return allowed[name] })
+}
+
+// AllowFunc lets the counters for which allow returns true be created, as
+// well as incrementing those already in the registry
+func AllowFunc(allow func(name string) bool) Option {
+	return func(c *config) {
+		c.allow = allow
+	}
+}
+
+// MaxCounters caps the counters created, ignoring lines which would take
+// the registry over n. Without AllowNames or AllowFunc it lets any new name
+// be created while there is room.
+func MaxCounters(n int) Option {
+	return func(c *config) {
+		c.maxCounters = n
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// counter returns the counter name refers to, and whether it exists or may
+// be created
+func (c *config) counter(g *ratecounter.Registry, name string) (*ratecounter.RateCounter, bool) {
+	if r, ok := g.Lookup(name); ok {
+		return r, true
+	}
+
+	switch {
+	case c.allow != nil && !c.allow(name):
+		return nil, false
+	case c.allow == nil && c.maxCounters <= 0:
+		return nil, false
+	case c.maxCounters > 0 && g.Len() >= c.maxCounters:
+		return nil, false
+	}
+	return g.Get(name), true
+}
+
+// ListenAndServe listens on the UDP address addr and applies increments to
+// g until the listener fails
+func ListenAndServe(addr string, g *ratecounter.Registry, opts ...Option) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return Serve(conn, g, opts...)
+}
+
+// Serve reads datagrams from conn and applies increments to g. Closing g
+// closes conn, ending Serve. It returns nil once conn is closed, or the
+// first other read error.
+func Serve(conn net.PacketConn, g *ratecounter.Registry, opts ...Option) error {
+	defer g.OnClose(conn)()
+
+	c := newConfig(opts)
+	buf := make([]byte, MaxDatagramSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if n > 0 {
+			c.apply(g, string(buf[:n]))
+		}
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// ServeContext is Serve, closing conn once ctx is done. It returns nil
+// then, as Serve does once conn is closed.
+func ServeContext(ctx context.Context, conn net.PacketConn, g *ratecounter.Registry, opts ...Option) error {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	return Serve(conn, g, opts...)
+}
+
+// Apply parses the lines of one datagram and applies them to g, returning
+// how many were valid and admitted
+func Apply(g *ratecounter.Registry, datagram string, opts ...Option) int {
+	return newConfig(opts).apply(g, datagram)
+}
+
+func (c *config) apply(g *ratecounter.Registry, datagram string) int {
+	applied := 0
+
+	for _, line := range strings.Split(datagram, "\n") {
+		name, value, ok := parse(strings.TrimSpace(line))
+		if !ok {
+			continue
+		}
+		r, ok := c.counter(g, name)
+		if !ok {
+			continue
+		}
+		r.Incr(value)
+		applied++
+	}
+
+	return applied
+}
+
+// parse reads "name value", "name", "name:value|c" or "name:value"
+func parse(line string) (string, int64, bool) {
+	if line == "" {
+		return "", 0, false
+	}
+
+	var name, value string
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		name, value = line[:i], line[i+1:]
+		if j := strings.IndexByte(value, '|'); j >= 0 {
+			if value[j+1:] != "c" {
+				// Only counters make sense for a rate
+				return "", 0, false
+			}
+			value = value[:j]
+		}
+	} else {
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return "", 0, false
+		}
+		name = fields[0]
+		if len(fields) == 2 {
+			value = fields[1]
+		}
+	}
+
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return "", 0, false
+	}
+	if value == "" {
+		return name, 1, true
+	}
+
+	val, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+
+	return name, val, true
+}
@@ -0,0 +1,136 @@
+package ratecounterudp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+)
+
+func TestApply(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Minute)
+
+	applied := Apply(g, "backups 2\nbackups\njobs:5|c\njobs:1\n\nbad line here\ntimer:5|ms\nnope x\n", AllowNames("backups", "jobs", "timer"))
+	if applied != 4 {
+		t.Error("Expected ", applied, " to equal ", 4)
+	}
+
+	if val := g.Get("backups").Rate(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+	if val := g.Get("jobs").Rate(); val != 6 {
+		t.Error("Expected ", val, " to equal ", 6)
+	}
+	if val := g.Get("timer").Rate(); val != 0 {
+		t.Error("Expected non-counter types to be ignored, got", val)
+	}
+}
+
+func TestApply_UnknownNames(t *testing.T) {
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	g.Get("known")
+
+	// By default only counters already in the registry are incremented
+	if applied := Apply(g, "known 1\nspoofed 1\n"); applied != 1 {
+		t.Error("Expected ", applied, " to equal ", 1)
+	}
+	if _, ok := g.Lookup("spoofed"); ok {
+		t.Error("Expected no counter to be created for an unknown name")
+	}
+
+	if applied := Apply(g, "a 1\nb 1\nc 1\n", AllowFunc(func(name string) bool { return name != "b" })); applied != 2 {
+		t.Error("Expected ", applied, " to equal ", 2)
+	}
+	if _, ok := g.Lookup("b"); ok {
+		t.Error("Expected b to be refused")
+	}
+
+	// MaxCounters admits new names while there is room
+	if applied := Apply(g, "d 1\ne 1\n", MaxCounters(4)); applied != 1 {
+		t.Error("Expected ", applied, " to equal ", 1)
+	}
+	if g.Len() != 4 {
+		t.Error("Expected ", g.Len(), " to equal ", 4)
+	}
+}
+
+func TestServe(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen on UDP:", err)
+	}
+
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	done := make(chan error)
+	go func() {
+		done <- Serve(conn, g, AllowNames("requests"))
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Write([]byte("requests 4"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for g.Get("requests").Rate() != 4 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if val := g.Get("requests").Rate(); val != 4 {
+		t.Error("Expected ", val, " to equal ", 4)
+	}
+
+	conn.Close()
+	if err := <-done; err != nil {
+		t.Error("Expected Serve to return nil once closed, got", err)
+	}
+}
+
+func TestServe_RegistryClose(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen on UDP:", err)
+	}
+
+	g := ratecounter.NewRegistry(1 * time.Minute)
+	done := make(chan error)
+	go func() {
+		done <- Serve(conn, g)
+	}()
+
+	g.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error("Expected Serve to return nil once the registry is closed, got", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Serve to return with the registry closed")
+	}
+}
+
+func TestServeContext(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen on UDP:", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- ServeContext(ctx, conn, ratecounter.NewRegistry(1*time.Minute))
+	}()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error("Expected ServeContext to return nil once cancelled, got", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected ServeContext to return with the context")
+	}
+}
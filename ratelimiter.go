@@ -0,0 +1,200 @@
+package ratecounter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A RateLimiter permits at most a fixed number of events in any interval,
+// measured with a sliding window RateCounter, so one library can both
+// measure and limit
+type RateLimiter struct {
+	counter *RateCounter
+	limit   int64
+	// The most events the burst budget holds, zero if there is none
+	burst int64
+	// How long the burst budget takes to refill from empty, in milliseconds
+	refill uint64
+	// The events left in the burst budget, as of refilled
+	budget   float64
+	refilled uint64
+	// The events which would have been refused, if the limiter is only
+	// shadowing the limit
+	rejected *RateCounter
+	// Makes checking the rate and recording the events one step
+	mu sync.Mutex
+}
+
+// NewRateLimiter constructs a new RateLimiter permitting limit events in
+// every interval, whose counter is built with opts
+func NewRateLimiter(limit int64, intrvl time.Duration, opts ...Option) *RateLimiter {
+	if limit < 0 {
+		panic("RateLimiter limit cannot be negative")
+	}
+
+	return &RateLimiter{
+		counter: NewRateCounter(intrvl, opts...),
+		limit:   limit,
+	}
+}
+
+// WithBurst gives the limiter a burst budget of size events, which may be
+// spent to go over the limit for a while. The budget starts full and
+// refills steadily, taking refill to go from empty to full. It is not safe
+// to call while the limiter is in use.
+func (l *RateLimiter) WithBurst(size int64, refill time.Duration) *RateLimiter {
+	if size < 0 {
+		panic("RateLimiter burst cannot be negative")
+	}
+	if refill <= 0 {
+		panic("RateLimiter burst refill must be positive")
+	}
+
+	l.burst = size
+	l.refill = uint64(refill.Nanoseconds() / 1000000)
+	if l.refill == 0 {
+		l.refill = 1
+	}
+	l.budget = float64(size)
+	l.refilled = l.counter.now()
+
+	return l
+}
+
+// WithShadow puts the limiter in shadow mode, for trying a limit out on
+// real traffic before enforcing it. Every event is permitted, but the
+// limiter keeps the state it would have if it were enforcing: Rate and the
+// burst budget only count the events it would have permitted, and those it
+// would have refused are counted by RejectedRate. It is not safe to call
+// while the limiter is in use.
+func (l *RateLimiter) WithShadow() *RateLimiter {
+	// The same window as the limiter's counter, on the same clock
+	c := l.counter
+	l.rejected = NewRateCounter(time.Duration(c.interval)*time.Millisecond,
+		WithResolution(c.resolution()), func(r *RateCounter) { r.clock = c.clock })
+
+	return l
+}
+
+// RejectedRate returns the number of events in the last interval which a
+// limiter in shadow mode would have refused. It is always zero unless the
+// limiter was put in shadow mode with WithShadow.
+func (l *RateLimiter) RejectedRate() int64 {
+	if l.rejected == nil {
+		return 0
+	}
+	return l.rejected.Rate()
+}
+
+// Allow reports whether one event may happen now, and records it if so
+func (l *RateLimiter) Allow() bool {
+	return l.AllowN(1)
+}
+
+// AllowN reports whether n events may happen now, and records them if so.
+// Either all n are permitted or none are. In shadow mode it always reports
+// true. It panics if n is negative, which would hand back room in the limit.
+func (l *RateLimiter) AllowN(n int64) bool {
+	if n < 0 {
+		panic("RateLimiter events cannot be negative")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.permit(n) {
+		if l.rejected != nil {
+			l.rejected.Incr(n)
+			return true
+		}
+		return false
+	}
+	l.counter.Incr(n)
+	return true
+}
+
+// permit reports whether the limit, or the burst budget, has room for n
+// events now, spending the budget if it is needed. The caller must hold
+// mu.
+func (l *RateLimiter) permit(n int64) bool {
+	rate := l.counter.Rate()
+	if over := l.over(rate, n); over > 0 {
+		budget := l.refillBudget()
+		if float64(over) > budget {
+			return false
+		}
+		l.budget = budget - float64(over)
+	}
+	return true
+}
+
+// over returns how many of n more events would go over the limit, on top
+// of those over it already
+func (l *RateLimiter) over(rate, n int64) int64 {
+	if rate+n <= l.limit {
+		return 0
+	}
+	if rate >= l.limit {
+		return n
+	}
+	return rate + n - l.limit
+}
+
+// refillBudget brings the burst budget up to date and returns it. The
+// caller must hold mu.
+func (l *RateLimiter) refillBudget() float64 {
+	if l.burst == 0 {
+		return 0
+	}
+
+	now := l.counter.now()
+	if now > l.refilled {
+		l.budget += float64(now-l.refilled) * float64(l.burst) / float64(l.refill)
+		if l.budget > float64(l.burst) {
+			l.budget = float64(l.burst)
+		}
+	}
+	l.refilled = now
+	return l.budget
+}
+
+// Burst returns the number of whole events left in the burst budget
+func (l *RateLimiter) Burst() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int64(l.refillBudget())
+}
+
+// Wait blocks until one event may happen and records it, or returns the
+// context's error if it is done first
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	// Room only appears as partials drop, so check once per partial
+	poll := time.Duration(l.counter.partialMillis()) * time.Millisecond
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if l.Allow() {
+			return nil
+		}
+		timer.Reset(poll)
+	}
+}
+
+// Rate returns the number of events permitted in the last interval
+func (l *RateLimiter) Rate() int64 {
+	return l.counter.Rate()
+}
+
+// Limit returns the number of events permitted in each interval
+func (l *RateLimiter) Limit() int64 {
+	return l.limit
+}
@@ -0,0 +1,191 @@
+package ratecounter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	clock := newManualClock()
+	l := NewRateLimiter(3, 1*time.Second, WithClock(clock))
+
+	for i := 0; i < 3; i++ {
+		if !l.Allow() {
+			t.Error("Expected event ", i, " to be allowed")
+		}
+	}
+	if l.Allow() {
+		t.Error("Expected the fourth event to be refused")
+	}
+	if val := l.Rate(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+
+	// Room returns as the window slides
+	clock.Add(1100 * time.Millisecond)
+	if !l.Allow() {
+		t.Error("Expected an event to be allowed once the window moved on")
+	}
+}
+
+func TestRateLimiter_AllowN(t *testing.T) {
+	l := NewRateLimiter(10, 1*time.Second)
+
+	if !l.AllowN(7) {
+		t.Error("Expected 7 events to be allowed")
+	}
+	// All or nothing
+	if l.AllowN(4) {
+		t.Error("Expected 4 more events to be refused")
+	}
+	if val := l.Rate(); val != 7 {
+		t.Error("Expected ", val, " to equal ", 7)
+	}
+	if !l.AllowN(3) {
+		t.Error("Expected 3 more events to be allowed")
+	}
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	l := NewRateLimiter(1, 100*time.Millisecond, WithResolution(4))
+
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Error("Expected to wait for the window, waited ", waited)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Error("Expected ", err, " to equal ", context.DeadlineExceeded)
+	}
+}
+
+func TestRateLimiter_WithBurst(t *testing.T) {
+	clock := newManualClock()
+	l := NewRateLimiter(3, 1*time.Second, WithClock(clock)).WithBurst(2, 10*time.Second)
+
+	if val := l.Burst(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+
+	// The steady limit is used first, then the burst
+	for i := 0; i < 5; i++ {
+		if !l.Allow() {
+			t.Error("Expected event ", i, " to be allowed")
+		}
+		if i < 3 && l.Burst() != 2 {
+			t.Error("Expected event ", i, " to leave the burst alone")
+		}
+	}
+	if l.Allow() {
+		t.Error("Expected the sixth event to be refused")
+	}
+	if val := l.Burst(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+
+	// Once the window moves on the steady limit is back, but the burst
+	// refills slowly
+	clock.Add(1100 * time.Millisecond)
+	if !l.AllowN(3) {
+		t.Error("Expected 3 events to be allowed")
+	}
+	if l.Allow() {
+		t.Error("Expected the burst to still be empty")
+	}
+
+	clock.Add(5 * time.Second)
+	if val := l.Burst(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+	clock.Add(time.Hour)
+	if val := l.Burst(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+}
+
+func TestRateLimiter_WithBurst_AllowN(t *testing.T) {
+	clock := newManualClock()
+	l := NewRateLimiter(10, 1*time.Second, WithClock(clock)).WithBurst(5, 1*time.Minute)
+
+	if !l.AllowN(8) {
+		t.Error("Expected 8 events to be allowed")
+	}
+	// 3 of these go over the limit, all or nothing
+	if l.AllowN(8) {
+		t.Error("Expected 8 more events to be refused")
+	}
+	if !l.AllowN(5) {
+		t.Error("Expected 5 more events to be allowed")
+	}
+	if val := l.Burst(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+	if val := l.Rate(); val != 13 {
+		t.Error("Expected ", val, " to equal ", 13)
+	}
+}
+
+func TestRateLimiter_WithShadow(t *testing.T) {
+	clock := newManualClock()
+	l := NewRateLimiter(3, 1*time.Second, WithClock(clock)).WithShadow()
+
+	for i := 0; i < 5; i++ {
+		if !l.Allow() {
+			t.Error("Expected event ", i, " to be allowed in shadow mode")
+		}
+	}
+	if !l.AllowN(4) {
+		t.Error("Expected 4 more events to be allowed in shadow mode")
+	}
+
+	// The limiter tracks what it would have done
+	if val := l.Rate(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+	if val := l.RejectedRate(); val != 6 {
+		t.Error("Expected ", val, " to equal ", 6)
+	}
+
+	clock.Add(1100 * time.Millisecond)
+	l.Allow()
+	if val := l.RejectedRate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+	if val := l.Rate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+}
+
+func TestRateLimiter_RejectedRate_Enforcing(t *testing.T) {
+	l := NewRateLimiter(1, 1*time.Second)
+
+	l.Allow()
+	l.Allow()
+	if val := l.RejectedRate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestRateLimiter_AllowN_Negative(t *testing.T) {
+	l := NewRateLimiter(10, 1*time.Second)
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Negative AllowN did not panic")
+		}
+		if val := l.counter.Rate(); val != 0 {
+			t.Error("Expected ", val, " to equal ", 0)
+		}
+	}()
+
+	l.AllowN(-10)
+}
@@ -0,0 +1,24 @@
+package ratecounter
+
+// An Incrementer records events. It is implemented by Counter, RateCounter
+// and AvgRateCounter.
+type Incrementer interface {
+	Incr(val int64)
+}
+
+// A Rater records events and reports how many happened in the last
+// interval. Libraries can accept a Rater so callers may swap in any
+// implementation, or a fake in tests.
+type Rater interface {
+	Incrementer
+	Rate() int64
+}
+
+var (
+	_ Incrementer = (*Counter)(nil)
+	_ Incrementer = (*AvgRateCounter)(nil)
+	_ Incrementer = (*ShardedCounter)(nil)
+	_ Rater       = (*RateCounter)(nil)
+	_ Rater       = (*EWMARateCounter)(nil)
+	_ Rater       = (*AdaptiveRateCounter)(nil)
+)
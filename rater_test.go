@@ -0,0 +1,28 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeRater struct {
+	total int64
+}
+
+func (f *fakeRater) Incr(val int64) { f.total += val }
+func (f *fakeRater) Rate() int64    { return f.total }
+
+func TestRater(t *testing.T) {
+	record := func(r Rater) int64 {
+		r.Incr(1)
+		r.Incr(2)
+		return r.Rate()
+	}
+
+	if val := record(NewRateCounter(1 * time.Second)); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+	if val := record(&fakeRater{}); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+}
@@ -0,0 +1,325 @@
+package ratecounter
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Registry is a thread-safe set of named RateCounters, created on first
+// use, so services can track counters for endpoints dynamically
+type Registry struct {
+	interval time.Duration
+	opts     []Option
+	counters map[string]*RateCounter
+	// When each unregistered name stops being reported as a tombstone, in
+	// milliseconds on the counters' clock
+	tombstones map[string]uint64
+	// Whether Close has been called
+	closed bool
+	// The background components Close closes too, in the order added
+	closers []*closer
+	mu      sync.RWMutex
+	// Taken by ConsistentSnapshot to hold off events while it reads, if
+	// built by NewFencedRegistry
+	fence *sync.RWMutex
+	// The counters' clock, in milliseconds
+	now func() uint64
+	// The tags every counter is built with
+	tags map[string]string
+}
+
+// NewRegistry constructs a new Registry whose counters cover the interval
+// provided and are built with opts
+func NewRegistry(intrvl time.Duration, opts ...Option) *Registry {
+	g := &Registry{
+		interval: intrvl,
+		opts:     opts,
+		counters: make(map[string]*RateCounter),
+	}
+
+	// Read the options' clock without building a live counter
+	probe := &RateCounter{}
+	for _, opt := range opts {
+		opt(probe)
+	}
+	g.now = probe.now
+	g.tags = probe.Tags()
+
+	return g
+}
+
+// Tags returns a copy of the tags every counter in the registry carries,
+// set by WithTags in the registry's options
+func (g *Registry) Tags() map[string]string {
+	if g.tags == nil {
+		return nil
+	}
+
+	tags := make(map[string]string, len(g.tags))
+	for k, v := range g.tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// NewFencedRegistry constructs a new Registry as NewRegistry does, whose
+// ConsistentSnapshot also holds off events while it reads the counters.
+// Every event added to its counters takes a read lock the counters share,
+// so it costs concurrent writers the scalability WithShards buys; use it
+// only where cross-counter ratios must be exact.
+func NewFencedRegistry(intrvl time.Duration, opts ...Option) *Registry {
+	g := NewRegistry(intrvl, opts...)
+	fence := &sync.RWMutex{}
+	g.fence = fence
+	g.opts = append(append([]Option{}, opts...), func(r *RateCounter) {
+		r.fence = fence
+	})
+	return g
+}
+
+// Get returns the counter with the given name, creating it if needed. After
+// Close, new counters are created closed, with no ticker.
+func (g *Registry) Get(name string) *RateCounter {
+	g.mu.RLock()
+	r, ok := g.counters[name]
+	g.mu.RUnlock()
+	if ok {
+		return r
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if r, ok := g.counters[name]; ok {
+		return r
+	}
+	r = NewRateCounter(g.interval, g.opts...)
+	if g.closed {
+		r.Close()
+	}
+	g.counters[name] = r
+	// A counter used again is no longer deleted
+	delete(g.tombstones, name)
+
+	return r
+}
+
+// Lookup returns the counter with the given name, and whether there is
+// one, without creating it
+func (g *Registry) Lookup(name string) (*RateCounter, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	r, ok := g.counters[name]
+	return r, ok
+}
+
+// Remove removes the counter with the given name, if there is one, and
+// stops its ticker
+func (g *Registry) Remove(name string) {
+	g.mu.Lock()
+	r := g.counters[name]
+	delete(g.counters, name)
+	g.mu.Unlock()
+
+	if r != nil {
+		r.Stop()
+	}
+}
+
+// Unregister removes the counter with the given name, but reports it from
+// Tombstones for period afterwards, so exporters can emit a final zero or
+// stale marker rather than the series silently disappearing. Getting the
+// name again before then revives it as a new counter. The counter's ticker
+// is stopped.
+func (g *Registry) Unregister(name string, period time.Duration) {
+	g.mu.Lock()
+	r, ok := g.counters[name]
+	if !ok {
+		g.mu.Unlock()
+		return
+	}
+	delete(g.counters, name)
+
+	if g.tombstones == nil {
+		g.tombstones = make(map[string]uint64)
+	}
+	g.tombstones[name] = g.now() + uint64(period/time.Millisecond)
+	g.mu.Unlock()
+
+	r.Stop()
+}
+
+// Stop stops the ticker of every counter in the registry, such as those
+// started by WithTicker, and waits for them to exit. The counters stay in
+// the registry and carry on working, but are only rotated as they are used.
+func (g *Registry) Stop() {
+	for _, e := range g.entries() {
+		e.counter.Stop()
+	}
+}
+
+// Tombstones returns the names of counters unregistered within their
+// tombstone period, in name order
+func (g *Registry) Tombstones() []string {
+	now := g.now()
+
+	g.mu.Lock()
+	names := make([]string, 0, len(g.tombstones))
+	for name, until := range g.tombstones {
+		if now > until {
+			delete(g.tombstones, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	g.mu.Unlock()
+
+	sort.Strings(names)
+	return names
+}
+
+// Each calls fn for every counter, in name order. The registry is not
+// locked while fn runs, so fn may use it.
+func (g *Registry) Each(fn func(name string, r *RateCounter)) {
+	for _, e := range g.entries() {
+		fn(e.name, e.counter)
+	}
+}
+
+type registryEntry struct {
+	name    string
+	counter *RateCounter
+}
+
+// entries returns a copy of the registry's counters, in name order
+func (g *Registry) entries() []registryEntry {
+	g.mu.RLock()
+	entries := make([]registryEntry, 0, len(g.counters))
+	for name, r := range g.counters {
+		entries = append(entries, registryEntry{name: name, counter: r})
+	}
+	g.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+	return entries
+}
+
+// Len returns the number of counters in the registry
+func (g *Registry) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.counters)
+}
+
+// Close closes every counter in the registry, stopping their tickers and
+// removing their watches, and closes any created later too. The counters
+// stay in the registry and carry on working. It then closes the components
+// added with OnClose, newest first, and returns the first error from them.
+// Later calls do nothing and return nil.
+func (g *Registry) Close() error {
+	g.mu.Lock()
+	g.closed = true
+	closers := g.closers
+	g.closers = nil
+	g.mu.Unlock()
+
+	for _, e := range g.entries() {
+		e.counter.Close()
+	}
+
+	var first error
+	for ii := len(closers) - 1; ii >= 0; ii-- {
+		if err := closers[ii].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// A closer is a component added with OnClose, by pointer so it can be
+// found again to remove
+type closer struct {
+	io.Closer
+}
+
+// OnClose ties c, such as a Pusher or a connection being served, to the
+// registry, so Close closes it too and one call tears everything down. If
+// the registry is already closed, c is closed now, and its error is lost;
+// the registry's Close has already returned. The returned function
+// unties c, reporting false if it has already been closed.
+func (g *Registry) OnClose(c io.Closer) (stop func() bool) {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		_ = c.Close()
+		return func() bool { return false }
+	}
+	entry := &closer{c}
+	g.closers = append(g.closers, entry)
+	g.mu.Unlock()
+
+	return func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		for ii, e := range g.closers {
+			if e == entry {
+				g.closers = append(g.closers[:ii], g.closers[ii+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Merge adds each counter in snap to the registry's counter of the same
+// name, creating it if needed, as RateCounter.Merge does
+func (g *Registry) Merge(snap RegistrySnapshot) {
+	for name, s := range snap.Counters {
+		g.Get(name).Merge(s)
+	}
+}
+
+// A RegistrySnapshot is a copy of every counter in a Registry, all taken at
+// the same instant
+type RegistrySnapshot struct {
+	// At is the instant every counter was brought up to
+	At time.Time
+	// Counters holds each counter's snapshot by name
+	Counters map[string]Snapshot
+}
+
+// ConsistentSnapshot returns a snapshot of every counter, each rotated to
+// the same instant first, so ratios derived across counters, such as errors
+// over requests, are not skewed by counters being read at slightly
+// different times. Events which arrive while it reads may be seen by some
+// counters and not others, unless the registry was built by
+// NewFencedRegistry, which holds them off until it is done.
+func (g *Registry) ConsistentSnapshot() RegistrySnapshot {
+	entries := g.entries()
+	now := g.now()
+	snap := RegistrySnapshot{
+		At:       time.Unix(0, int64(now)*int64(time.Millisecond)),
+		Counters: make(map[string]Snapshot, len(entries)),
+	}
+
+	// Rotate outside the fence, so OnRotate callbacks and watches may
+	// increment counters in the registry
+	for _, e := range entries {
+		e.counter.advance(now)
+	}
+
+	if g.fence != nil {
+		g.fence.Lock()
+		defer g.fence.Unlock()
+	}
+
+	for _, e := range entries {
+		var s Snapshot
+		e.counter.snapshotAt(&s, now)
+		snap.Counters[e.name] = s
+	}
+
+	return snap
+}
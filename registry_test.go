@@ -0,0 +1,350 @@
+package ratecounter
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	g := NewRegistry(1*time.Second, WithResolution(5))
+
+	users := g.Get("/users")
+	users.Incr(2)
+	if g.Get("/users") != users {
+		t.Error("Expected Get to return the same counter")
+	}
+	if len(users.partials) != 5 {
+		t.Error("Expected ", len(users.partials), " partials to equal ", 5)
+	}
+	g.Get("/orders").Incr(1)
+
+	var names []string
+	var rates []int64
+	g.Each(func(name string, r *RateCounter) {
+		names = append(names, name)
+		rates = append(rates, r.Rate())
+	})
+	if !reflect.DeepEqual(names, []string{"/orders", "/users"}) {
+		t.Error("Expected ", names, " to equal ", []string{"/orders", "/users"})
+	}
+	if !reflect.DeepEqual(rates, []int64{1, 2}) {
+		t.Error("Expected ", rates, " to equal ", []int64{1, 2})
+	}
+
+	g.Remove("/users")
+	g.Remove("/missing")
+	if g.Len() != 1 {
+		t.Error("Expected ", g.Len(), " to equal ", 1)
+	}
+	if val := g.Get("/users").Rate(); val != 0 {
+		t.Error("Expected a new counter after Remove, got rate", val)
+	}
+}
+
+func TestRegistry_EachCanUseRegistry(t *testing.T) {
+	g := NewRegistry(1 * time.Second)
+	g.Get("a")
+
+	g.Each(func(name string, r *RateCounter) {
+		g.Get(name + "-copy")
+		g.Remove(name)
+	})
+	if g.Len() != 1 {
+		t.Error("Expected ", g.Len(), " to equal ", 1)
+	}
+}
+
+func TestRegistry_Concurrent(t *testing.T) {
+	g := NewRegistry(1 * time.Second)
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				g.Get("shared").Incr(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if val := g.Get("shared").Rate(); val != 1000 {
+		t.Error("Expected ", val, " to equal ", 1000)
+	}
+}
+
+func TestRegistry_ConsistentSnapshot(t *testing.T) {
+	clock := newManualClock()
+	g := NewRegistry(200*time.Millisecond, WithResolution(4), WithClock(clock))
+
+	// Even an empty registry reads the counters' clock
+	if snap := g.ConsistentSnapshot(); len(snap.Counters) != 0 {
+		t.Error("Expected ", snap.Counters, " to be empty")
+	} else if !snap.At.Equal(clock.Now()) {
+		t.Error("Expected ", snap.At, " to equal ", clock.Now())
+	}
+
+	g.Get("requests").Incr(10)
+	g.Get("errors").Incr(1)
+	clock.Add(100 * time.Millisecond)
+	g.Get("requests").Incr(10)
+
+	// Every counter is rotated to the same instant, even ones which have
+	// not been touched since
+	clock.Add(150 * time.Millisecond)
+	snap := g.ConsistentSnapshot()
+
+	if !snap.At.Equal(clock.Now()) {
+		t.Error("Expected ", snap.At, " to equal ", clock.Now())
+	}
+	if val := snap.Counters["requests"].Rate; val != 10 {
+		t.Error("Expected ", val, " to equal ", 10)
+	}
+	if val := snap.Counters["errors"].Rate; val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+	if !snap.Counters["requests"].Start.Equal(snap.Counters["errors"].Start) {
+		t.Error("Expected ", snap.Counters["requests"].Start, " to equal ", snap.Counters["errors"].Start)
+	}
+}
+
+func TestRegistry_ConsistentSnapshotFence(t *testing.T) {
+	clock := newManualClock()
+	var g *Registry
+	// Rotations increment another counter in the registry, which must not
+	// deadlock against the fence
+	g = NewFencedRegistry(200*time.Millisecond, WithResolution(4), WithClock(clock), OnRotate(func(dropped int64, at time.Time) {
+		if dropped > 0 {
+			g.Get("rotated").Incr(1)
+		}
+	}))
+
+	g.Get("requests").Incr(10)
+	clock.Add(100 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				g.Get("requests").Incr(1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			g.ConsistentSnapshot()
+		}()
+	}
+	wg.Wait()
+
+	snap := g.ConsistentSnapshot()
+	if val := snap.Counters["requests"].Rate; val != 1010 {
+		t.Error("Expected ", val, " to equal ", 1010)
+	}
+
+	clock.Add(250 * time.Millisecond)
+	snap = g.ConsistentSnapshot()
+	if val := snap.Counters["requests"].Rate; val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+	if _, ok := g.Lookup("rotated"); !ok {
+		t.Error("Expected the rotation callback to have run")
+	}
+
+	// Only fenced registries' counters take the fence
+	if g.Get("requests").fence == nil {
+		t.Error("Expected a fenced registry's counters to share its fence")
+	}
+	if NewRegistry(time.Second).Get("requests").fence != nil {
+		t.Error("Expected a plain registry's counters to have no fence")
+	}
+}
+
+func TestRegistry_Tags(t *testing.T) {
+	g := NewRegistry(time.Second, WithTags(map[string]string{"service": "api"}))
+
+	tags := g.Tags()
+	if tags["service"] != "api" || len(tags) != 1 {
+		t.Error("Expected ", tags, " to equal ", map[string]string{"service": "api"})
+	}
+	tags["service"] = "changed"
+	if val := g.Get("requests").Tags()["service"]; val != "api" {
+		t.Error("Expected ", val, " to equal ", "api")
+	}
+	if tags := NewRegistry(time.Second).Tags(); tags != nil {
+		t.Error("Expected ", tags, " to be nil")
+	}
+}
+
+func TestRegistry_Unregister(t *testing.T) {
+	g := NewRegistry(1 * time.Minute)
+	g.Get("gone").Incr(1)
+	g.Get("stays").Incr(1)
+
+	// Unknown names leave no tombstone
+	g.Unregister("never", time.Minute)
+
+	g.Unregister("gone", 50*time.Millisecond)
+	if g.Len() != 1 {
+		t.Error("Expected ", g.Len(), " to equal ", 1)
+	}
+	if names := g.Tombstones(); len(names) != 1 || names[0] != "gone" {
+		t.Error("Expected ", names, " to equal ", []string{"gone"})
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if names := g.Tombstones(); len(names) != 0 {
+		t.Error("Expected ", names, " to be empty")
+	}
+}
+
+func TestRegistry_UnregisterWithClock(t *testing.T) {
+	clock := newManualClock()
+	g := NewRegistry(1*time.Minute, WithClock(clock))
+	g.Get("gone").Incr(1)
+	g.Unregister("gone", time.Hour)
+
+	// Tombstones follow the counters' clock, not the wall clock
+	clock.Add(59 * time.Minute)
+	if names := g.Tombstones(); len(names) != 1 || names[0] != "gone" {
+		t.Error("Expected ", names, " to equal ", []string{"gone"})
+	}
+
+	clock.Add(2 * time.Minute)
+	if names := g.Tombstones(); len(names) != 0 {
+		t.Error("Expected ", names, " to be empty")
+	}
+}
+
+func TestRegistry_UnregisterRevived(t *testing.T) {
+	g := NewRegistry(1 * time.Minute)
+	g.Get("flappy").Incr(5)
+	g.Unregister("flappy", time.Minute)
+
+	if val := g.Get("flappy").Rate(); val != 0 {
+		t.Error("Expected a new counter, got rate ", val)
+	}
+	if names := g.Tombstones(); len(names) != 0 {
+		t.Error("Expected ", names, " to be empty")
+	}
+}
+
+func TestRegistry_StopsTickers(t *testing.T) {
+	g := NewRegistry(100*time.Millisecond, WithTicker())
+
+	removed := g.Get("removed")
+	unregistered := g.Get("unregistered")
+	kept := g.Get("kept")
+	if removed.ticker == nil || unregistered.ticker == nil || kept.ticker == nil {
+		t.Fatal("Expected WithTicker to start every counter's ticker")
+	}
+
+	// Counters leaving the registry take their goroutine with them
+	g.Remove("removed")
+	g.Unregister("unregistered", time.Minute)
+	if removed.ticker != nil || unregistered.ticker != nil {
+		t.Error("Expected removed counters to be stopped")
+	}
+	if kept.ticker == nil {
+		t.Error("Expected the remaining counter to keep its ticker")
+	}
+
+	g.Stop()
+	if kept.ticker != nil {
+		t.Error("Expected Stop to stop every counter")
+	}
+	kept.Incr(1)
+	if val := kept.Rate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+}
+
+func TestRegistry_Close(t *testing.T) {
+	g := NewRegistry(100*time.Millisecond, WithTicker())
+	before := g.Get("before")
+
+	for i := 0; i < 2; i++ {
+		if err := g.Close(); err != nil {
+			t.Error("Expected ", err, " to be nil")
+		}
+	}
+	if before.ticker != nil {
+		t.Error("Expected Close to stop every counter")
+	}
+
+	// Counters made after Close start closed, but still count
+	after := g.Get("after")
+	if after.ticker != nil {
+		t.Error("Expected new counters to be closed")
+	}
+	after.Incr(2)
+	if val := after.Rate(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+}
+
+func TestRegistry_Merge(t *testing.T) {
+	clock := newManualClock()
+	old := NewRegistry(1*time.Second, WithClock(clock))
+	old.Get("/users").Incr(3)
+	old.Get("/orders").Incr(1)
+
+	g := NewRegistry(1*time.Second, WithClock(clock))
+	g.Get("/users").Incr(1)
+	g.Merge(old.ConsistentSnapshot())
+
+	if val := g.Get("/users").Rate(); val != 4 {
+		t.Error("Expected ", val, " to equal ", 4)
+	}
+	if val := g.Get("/orders").Rate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+}
+
+// closeRecorder records the order it and others like it are closed in
+type closeRecorder struct {
+	name  string
+	order *[]string
+	err   error
+}
+
+func (c *closeRecorder) Close() error {
+	*c.order = append(*c.order, c.name)
+	return c.err
+}
+
+func TestRegistry_OnClose(t *testing.T) {
+	g := NewRegistry(1 * time.Second)
+	var order []string
+	failed := errors.New("failed")
+	g.OnClose(&closeRecorder{name: "first", order: &order, err: failed})
+	g.OnClose(&closeRecorder{name: "second", order: &order})
+	stop := g.OnClose(&closeRecorder{name: "untied", order: &order})
+	if !stop() {
+		t.Error("Expected stop to untie the closer")
+	}
+
+	if err := g.Close(); err != failed {
+		t.Error("Expected ", err, " to equal ", failed)
+	}
+	if !reflect.DeepEqual(order, []string{"second", "first"}) {
+		t.Error("Expected ", order, " to equal ", []string{"second", "first"})
+	}
+	if stop() {
+		t.Error("Expected stop to report the closer already untied")
+	}
+
+	// Once closed, new components are closed at once
+	if err := g.Close(); err != nil {
+		t.Error("Expected ", err, " to be nil")
+	}
+	g.OnClose(&closeRecorder{name: "late", order: &order})
+	if len(order) != 3 || order[2] != "late" {
+		t.Error("Expected ", order, " to end with late")
+	}
+}
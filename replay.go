@@ -0,0 +1,77 @@
+package ratecounter
+
+import (
+	"sync"
+	"time"
+)
+
+// An Event is one scripted call to a counter in a Replay
+type Event struct {
+	// At is when the event happens
+	At time.Time
+	// Val is passed to Incr. Events with a Val of zero only read the rate.
+	Val int64
+}
+
+// A Replay drives a RateCounter from scripted timestamps instead of the
+// wall clock, so windowing behaviour can be reproduced exactly, e.g. from a
+// bug report or a test fixture. Time only moves when an event says so.
+type Replay struct {
+	counter *RateCounter
+	// The scripted time, in milliseconds since the epoch
+	millis uint64
+	mu     sync.Mutex
+}
+
+// NewReplay constructs a new Replay of a RateCounter for the interval and
+// options provided, starting at start
+func NewReplay(intrvl time.Duration, start time.Time, opts ...Option) *Replay {
+	p := &Replay{millis: toMillis(start)}
+	opts = append(opts[:len(opts):len(opts)], func(r *RateCounter) {
+		r.clock = p.now
+	})
+	p.counter = NewRateCounter(intrvl, opts...)
+
+	return p
+}
+
+func toMillis(t time.Time) uint64 {
+	return uint64(t.UnixNano() / 1000000)
+}
+
+func (p *Replay) now() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.millis
+}
+
+// advance moves the scripted time to t. Time never moves backwards.
+func (p *Replay) advance(t time.Time) {
+	millis := toMillis(t)
+
+	p.mu.Lock()
+	if millis > p.millis {
+		p.millis = millis
+	}
+	p.mu.Unlock()
+}
+
+// Counter returns the counter being replayed
+func (p *Replay) Counter() *RateCounter {
+	return p.counter
+}
+
+// Run applies each event in order and returns the rate after each one
+func (p *Replay) Run(events []Event) []int64 {
+	rates := make([]int64, len(events))
+
+	for i, e := range events {
+		p.advance(e.At)
+		if e.Val != 0 {
+			p.counter.Incr(e.Val)
+		}
+		rates[i] = p.counter.Rate()
+	}
+
+	return rates
+}
@@ -0,0 +1,50 @@
+package ratecounter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	start := time.Date(2018, time.October, 8, 12, 0, 0, 0, time.UTC)
+	at := func(millis int) time.Time {
+		return start.Add(time.Duration(millis) * time.Millisecond)
+	}
+
+	events := []Event{
+		{At: at(0), Val: 1},
+		{At: at(150), Val: 2},
+		{At: at(400), Val: 4},
+		{At: at(650)},
+		{At: at(900)},
+		{At: at(2000)},
+	}
+
+	// Replaying the same script always gives the same rates
+	for i := 0; i < 3; i++ {
+		p := NewReplay(500*time.Millisecond, start, WithResolution(5))
+
+		// Each event drops out exactly an interval after its partial began
+		rates := p.Run(events)
+		expected := []int64{1, 3, 7, 4, 0, 0}
+		if !reflect.DeepEqual(rates, expected) {
+			t.Error("Expected ", rates, " to equal ", expected)
+		}
+	}
+}
+
+func TestReplay_TimeDoesNotGoBackwards(t *testing.T) {
+	start := time.Date(2018, time.October, 8, 12, 0, 0, 0, time.UTC)
+	p := NewReplay(1*time.Second, start)
+
+	rates := p.Run([]Event{
+		{At: start, Val: 1},
+		{At: start.Add(2 * time.Second), Val: 1},
+		{At: start, Val: 1},
+	})
+	expected := []int64{1, 1, 2}
+	if !reflect.DeepEqual(rates, expected) {
+		t.Error("Expected ", rates, " to equal ", expected)
+	}
+}
@@ -0,0 +1,86 @@
+package ratecounter
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// A SampledRateCounter is a RateCounter which also keeps a small, uniform
+// reservoir sample of the labels (request IDs, keys, ...) passed to
+// 'IncrWith', so a spike in the rate comes with example offenders
+type SampledRateCounter struct {
+	*RateCounter
+	size     int
+	interval time.Duration
+	// Samples for the current and previous interval
+	current, previous []string
+	// Events seen in the current interval
+	seen    int64
+	started time.Time
+	rand    *rand.Rand
+	mu      sync.Mutex
+}
+
+// NewSampledRateCounter constructs a new SampledRateCounter for the interval
+// provided, keeping up to size samples per interval, with opts applied to
+// its RateCounter. WithClock times the reservoir too.
+func NewSampledRateCounter(intrvl time.Duration, size int, opts ...Option) *SampledRateCounter {
+	if size < 1 {
+		panic("SampledRateCounter size cannot be less than 1")
+	}
+
+	r := NewRateCounter(intrvl, opts...)
+	return &SampledRateCounter{
+		RateCounter: r,
+		size:        size,
+		interval:    intrvl,
+		current:     make([]string, 0, size),
+		started:     clockTime(r.clock),
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// roll starts a new reservoir once the interval is over. The caller must
+// hold the lock.
+func (s *SampledRateCounter) roll(now time.Time) {
+	elapsed := now.Sub(s.started)
+	if elapsed < s.interval {
+		return
+	}
+
+	if elapsed < 2*s.interval {
+		s.previous, s.current = s.current, s.previous[:0]
+	} else {
+		s.previous, s.current = s.previous[:0], s.current[:0]
+	}
+	s.seen = 0
+	s.started = now
+}
+
+// IncrWith Add an event into the counter, offering label to the sample
+func (s *SampledRateCounter) IncrWith(val int64, label string) {
+	s.RateCounter.IncrWith(val, label)
+
+	s.mu.Lock()
+	s.roll(clockTime(s.clock))
+	s.seen++
+	if len(s.current) < s.size {
+		s.current = append(s.current, label)
+	} else if i := s.rand.Int63n(s.seen); i < int64(s.size) {
+		s.current[i] = label
+	}
+	s.mu.Unlock()
+}
+
+// Samples returns labels sampled from roughly the last interval, or up to
+// two intervals when the window has just moved on
+func (s *SampledRateCounter) Samples() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roll(clockTime(s.clock))
+
+	samples := make([]string, 0, len(s.current)+len(s.previous))
+	samples = append(samples, s.current...)
+	return append(samples, s.previous...)
+}
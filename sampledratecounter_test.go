@@ -0,0 +1,55 @@
+package ratecounter
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSampledRateCounter(t *testing.T) {
+	interval := 200 * time.Millisecond
+	clock := newManualClock()
+	s := NewSampledRateCounter(interval, 5, WithClock(clock))
+
+	for i := 0; i < 100; i++ {
+		s.IncrWith(1, strconv.Itoa(i))
+	}
+
+	if val := s.Rate(); val != 100 {
+		t.Error("Expected ", val, " to equal ", 100)
+	}
+
+	samples := s.Samples()
+	if len(samples) != 5 {
+		t.Fatal("Expected ", len(samples), " samples to equal ", 5)
+	}
+	seen := map[string]bool{}
+	for _, sample := range samples {
+		if seen[sample] {
+			t.Error("Sample ", sample, " appeared twice")
+		}
+		seen[sample] = true
+	}
+
+	// The previous interval's samples are kept for one more interval
+	clock.Add(interval + 50*time.Millisecond)
+	s.IncrWith(1, "new")
+	if samples := s.Samples(); len(samples) != 6 {
+		t.Error("Expected ", len(samples), " samples to equal ", 6)
+	}
+
+	clock.Add(3 * interval)
+	if samples := s.Samples(); len(samples) != 0 {
+		t.Error("Expected ", samples, " to be empty")
+	}
+}
+
+func TestSampledRateCounterMinSize(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Size < 1 did not panic")
+		}
+	}()
+
+	NewSampledRateCounter(1*time.Second, 0)
+}
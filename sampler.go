@@ -0,0 +1,54 @@
+package ratecounter
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// A Sampler decides whether to log an event, allowing at most a fixed
+// number per second plus one in every M of the overflow, and counts what it
+// suppressed so the total can be reported periodically. It tames log
+// floods without hiding them completely.
+type Sampler struct {
+	// Accessed atomically, so kept first for 64-bit alignment
+	overflowed uint64
+	suppressed uint64
+	rate       *RateCounter
+	perSecond  int64
+	overflow   int64
+}
+
+// NewSampler constructs a new Sampler allowing perSecond events a second,
+// then one in every overflowEvery events beyond that. An overflowEvery of
+// zero drops all of the overflow.
+func NewSampler(perSecond, overflowEvery int64) *Sampler {
+	if perSecond < 0 || overflowEvery < 0 {
+		panic("Sampler limits cannot be negative")
+	}
+
+	return &Sampler{
+		rate:      NewRateCounter(1 * time.Second),
+		perSecond: perSecond,
+		overflow:  overflowEvery,
+	}
+}
+
+// ShouldLog records an event and reports whether it should be logged
+func (s *Sampler) ShouldLog() bool {
+	if s.rate.IncrAndRate(1) <= s.perSecond {
+		return true
+	}
+
+	if s.overflow > 0 && atomic.AddUint64(&s.overflowed, 1)%uint64(s.overflow) == 0 {
+		return true
+	}
+
+	atomic.AddUint64(&s.suppressed, 1)
+	return false
+}
+
+// Suppressed returns the number of events suppressed since the last call,
+// and resets it
+func (s *Sampler) Suppressed() int64 {
+	return int64(atomic.SwapUint64(&s.suppressed, 0))
+}
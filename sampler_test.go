@@ -0,0 +1,47 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	s := NewSampler(3, 10)
+
+	logged := 0
+	for i := 0; i < 33; i++ {
+		if s.ShouldLog() {
+			logged++
+		}
+	}
+
+	// Three within the limit, then 1 in 10 of the other 30
+	if logged != 6 {
+		t.Error("Expected ", logged, " to equal ", 6)
+	}
+	if val := s.Suppressed(); val != 27 {
+		t.Error("Expected suppressed ", val, " to equal ", 27)
+	}
+	if val := s.Suppressed(); val != 0 {
+		t.Error("Expected suppressed ", val, " to equal ", 0)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if !s.ShouldLog() {
+		t.Error("Expected a new second to allow logging")
+	}
+}
+
+func TestSampler_NoOverflow(t *testing.T) {
+	s := NewSampler(1, 0)
+
+	s.ShouldLog()
+	for i := 0; i < 10; i++ {
+		if s.ShouldLog() {
+			t.Error("Expected overflow to be suppressed")
+		}
+	}
+	if val := s.Suppressed(); val != 10 {
+		t.Error("Expected suppressed ", val, " to equal ", 10)
+	}
+}
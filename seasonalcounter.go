@@ -0,0 +1,106 @@
+package ratecounter
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A SeasonalCounter is a rate counter which also keeps one season of coarse
+// history, e.g. one day or one week, so the current rate can be compared
+// with the same time in the previous season
+type SeasonalCounter struct {
+	// Only reached through Incr and Rate, so every event is also recorded
+	// in the history
+	counter *RateCounter
+	// The width of each history slot, one interval, in milliseconds
+	slot int64
+	// One season of slots, plus the one being filled
+	history []seasonalSlot
+	mu      sync.Mutex
+}
+
+type seasonalSlot struct {
+	// Which slot since the epoch this holds
+	epoch int64
+	value int64
+}
+
+// NewSeasonalCounter constructs a new SeasonalCounter for the interval
+// provided, keeping history for season, e.g. 24 * time.Hour to compare with
+// yesterday. History is kept in interval-wide slots, so a season of one week
+// and an interval of one minute keeps about 10000 of them. The options
+// configure the underlying RateCounter, and its clock is used for the
+// history too.
+func NewSeasonalCounter(intrvl, season time.Duration, opts ...Option) *SeasonalCounter {
+	if intrvl < time.Millisecond || season < intrvl {
+		panic("SeasonalCounter season must be at least one interval")
+	}
+
+	return &SeasonalCounter{
+		counter: NewRateCounter(intrvl, opts...),
+		slot:    int64(intrvl / time.Millisecond),
+		history: make([]seasonalSlot, int(season/intrvl)+1),
+	}
+}
+
+// epoch returns which slot since the epoch the counter's clock is in
+func (s *SeasonalCounter) epoch() int64 {
+	return int64(s.counter.now()) / s.slot
+}
+
+// Incr Add an event into the counter and its history
+func (s *SeasonalCounter) Incr(val int64) {
+	s.counter.Incr(val)
+
+	epoch := s.epoch()
+	s.mu.Lock()
+	slot := &s.history[epoch%int64(len(s.history))]
+	if slot.epoch != epoch {
+		slot.epoch, slot.value = epoch, 0
+	}
+	slot.value += val
+	s.mu.Unlock()
+}
+
+// Baseline Return the number of events in the interval-wide slot one season
+// ago
+func (s *SeasonalCounter) Baseline() int64 {
+	// The slot one season back is the one after the current slot in the ring
+	epoch := s.epoch() - int64(len(s.history)-1)
+	if epoch < 0 {
+		// The clock is within one season of 1970, so there is no baseline
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slot := s.history[epoch%int64(len(s.history))]
+	if slot.epoch != epoch {
+		return 0
+	}
+	return slot.value
+}
+
+// Rate Return the current number of events in the last interval
+func (s *SeasonalCounter) Rate() int64 {
+	return s.counter.Rate()
+}
+
+// DeviationFromBaseline Return how much the current rate differs from the
+// baseline, as a percentage of the baseline, e.g. 25 when traffic is 25%
+// above the same time last season. It returns 0 without a baseline.
+func (s *SeasonalCounter) DeviationFromBaseline() float64 {
+	rate, baseline := s.Rate(), s.Baseline()
+
+	if baseline == 0 {
+		return 0 // Avoid division by zero
+	}
+
+	return float64(rate-baseline) / float64(baseline) * 100
+}
+
+func (s *SeasonalCounter) String() string {
+	return strconv.FormatInt(s.Rate(), 10)
+}
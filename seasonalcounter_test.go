@@ -0,0 +1,78 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeasonalCounter(t *testing.T) {
+	interval := 100 * time.Millisecond
+	s := NewSeasonalCounter(interval, 3*interval)
+
+	if len(s.history) != 4 {
+		t.Fatal("Expected ", len(s.history), " slots to equal ", 4)
+	}
+
+	// Start at the beginning of a slot
+	time.Sleep(interval - time.Duration(time.Now().UnixNano()%int64(interval)) + 10*time.Millisecond)
+	s.Incr(4)
+	if val := s.Baseline(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+
+	// Same slot one season later
+	time.Sleep(3 * interval)
+	s.Incr(5)
+	if val := s.Baseline(); val != 4 {
+		t.Error("Expected baseline ", val, " to equal ", 4)
+	}
+	if val := s.DeviationFromBaseline(); val != 25 {
+		t.Error("Expected deviation ", val, " to equal ", 25)
+	}
+
+	// Two seasons later the history has been overwritten
+	time.Sleep(6 * interval)
+	if val := s.Baseline(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestSeasonalCounter_WithClock(t *testing.T) {
+	clock := newManualClock()
+	s := NewSeasonalCounter(time.Hour, 24*time.Hour, WithClock(clock))
+
+	s.Incr(4)
+	if val := s.Rate(); val != 4 {
+		t.Error("Expected ", val, " to equal ", 4)
+	}
+
+	// The history follows the counter's clock, not the wall clock
+	clock.Add(24 * time.Hour)
+	s.Incr(5)
+	if val := s.Baseline(); val != 4 {
+		t.Error("Expected baseline ", val, " to equal ", 4)
+	}
+	if val := s.Rate(); val != 5 {
+		t.Error("Expected ", val, " to equal ", 5)
+	}
+}
+
+func TestSeasonalCounter_NearEpoch(t *testing.T) {
+	clock := &manualClock{now: time.Unix(10, 0)}
+	s := NewSeasonalCounter(time.Second, time.Minute, WithClock(clock))
+
+	s.Incr(1)
+	if val := s.Baseline(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestSeasonalCounter_InvalidSeason(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Season shorter than the interval did not panic")
+		}
+	}()
+
+	NewSeasonalCounter(time.Minute, time.Second)
+}
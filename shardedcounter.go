@@ -0,0 +1,72 @@
+package ratecounter
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// The size of a CPU cache line, which shards are padded out to
+const cacheLine = 64
+
+// shard is one slot of a ShardedCounter, alone on its cache line so
+// goroutines on different CPUs don't contend for it
+type shard struct {
+	value int64
+	_     [cacheLine - 8]byte
+}
+
+// A ShardedCounter is a thread-safe counter which spreads increments over
+// several shards, each on its own cache line, so heavily concurrent
+// increments don't all contend for one word. Reading the value sums the
+// shards, so it suits counters written far more often than they are read.
+type ShardedCounter struct {
+	shards []shard
+}
+
+// NewShardedCounter Constructs a new ShardedCounter with the number of
+// shards given, rounded up to a power of two. A few times GOMAXPROCS is a
+// good choice.
+func NewShardedCounter(shards int) *ShardedCounter {
+	if shards < 1 {
+		panic("ShardedCounter needs at least one shard")
+	}
+
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+	return &ShardedCounter{shards: make([]shard, n)}
+}
+
+// pick returns the shard for the calling goroutine. Go has no cheap way to
+// find the current CPU or goroutine, but each goroutine has its own stack,
+// so the address of a local variable separates them well enough.
+func (c *ShardedCounter) pick() *int64 {
+	var local byte
+	addr := uint64(uintptr(unsafe.Pointer(&local)))
+	// Mix the bits above the frame so neighbouring stacks spread out
+	addr = (addr >> 11) * 0x9E3779B97F4A7C15
+	return &c.shards[(addr>>32)&uint64(len(c.shards)-1)].value
+}
+
+// Incr method increments the counter by some value
+func (c *ShardedCounter) Incr(val int64) {
+	atomic.AddInt64(c.pick(), val)
+}
+
+// Value method returns the sum of every shard. Increments which happen
+// while it runs may or may not be included.
+func (c *ShardedCounter) Value() int64 {
+	var sum int64
+	for ii := range c.shards {
+		sum += atomic.LoadInt64(&c.shards[ii].value)
+	}
+	return sum
+}
+
+// Reset method resets every shard to zero
+func (c *ShardedCounter) Reset() {
+	for ii := range c.shards {
+		atomic.StoreInt64(&c.shards[ii].value, 0)
+	}
+}
@@ -0,0 +1,81 @@
+package ratecounter
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestShardedCounter(t *testing.T) {
+	c := NewShardedCounter(5)
+	if len(c.shards) != 8 {
+		t.Error("Expected ", len(c.shards), " shards to equal ", 8)
+	}
+	if size := unsafe.Sizeof(shard{}); size != cacheLine {
+		t.Error("Expected shard size ", size, " to equal ", cacheLine)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Incr(2)
+			}
+		}()
+	}
+	wg.Wait()
+
+	c.Incr(-1)
+	if val := c.Value(); val != 99999 {
+		t.Error("Expected ", val, " to equal ", 99999)
+	}
+
+	c.Reset()
+	if val := c.Value(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func BenchmarkCounter_Parallel(b *testing.B) {
+	var c Counter
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Incr(1)
+		}
+	})
+}
+
+func BenchmarkShardedCounter_Parallel(b *testing.B) {
+	c := NewShardedCounter(4 * runtime.GOMAXPROCS(0))
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Incr(1)
+		}
+	})
+}
+
+func BenchmarkRateCounter_Incr_Parallel(b *testing.B) {
+	r := NewRateCounter(1 * time.Second)
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			r.Incr(1)
+		}
+	})
+}
+
+func BenchmarkRateCounter_Incr_WithShards_Parallel(b *testing.B) {
+	r := NewRateCounter(1*time.Second, WithShards(4*runtime.GOMAXPROCS(0)))
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			r.Incr(1)
+		}
+	})
+}
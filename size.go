@@ -0,0 +1,66 @@
+package ratecounter
+
+import "unsafe"
+
+// Rough per-entry overhead of a map, on top of the keys and values
+const mapEntryOverhead = 16
+
+// SizeBytes returns an estimate of the memory used by the counter, for
+// capacity planning. It counts the counter and the buffers it owns, such as
+// its trace, watches and ticker, and approximates the overhead of maps. The
+// channels watches send on belong to the caller and are not counted.
+func (r *RateCounter) SizeBytes() int {
+	size := int(unsafe.Sizeof(*r))
+	size += cap(r.partials) * int(unsafe.Sizeof(partial{}))
+	for ii := range r.partials {
+		if s := r.partials[ii].shards; s != nil {
+			size += int(unsafe.Sizeof(*s)) + cap(s.shards)*int(unsafe.Sizeof(shard{}))
+		}
+	}
+	size += len(r.unit) + len(r.description)
+
+	for k, v := range r.tags {
+		size += 2*int(unsafe.Sizeof(k)) + len(k) + len(v) + mapEntryOverhead
+	}
+
+	if t := r.trace; t != nil {
+		t.Lock()
+		size += int(unsafe.Sizeof(*t)) + cap(t.entries)*int(unsafe.Sizeof(TraceEntry{}))
+		for _, e := range t.entries {
+			size += len(e.Label)
+		}
+		t.Unlock()
+	}
+
+	r.Lock()
+	size += cap(r.watches)*int(unsafe.Sizeof(&Watch{})) + len(r.watches)*int(unsafe.Sizeof(Watch{}))
+	if r.ticker != nil {
+		size += int(unsafe.Sizeof(*r.ticker))
+	}
+	r.Unlock()
+
+	return size
+}
+
+// SizeBytes returns an estimate of the memory used by the counter
+func (a *AvgRateCounter) SizeBytes() int {
+	return int(unsafe.Sizeof(*a)) + a.hits.SizeBytes() + a.counter.SizeBytes()
+}
+
+// SizeBytes returns an estimate of the memory used by the registry and
+// every counter in it, for capacity planning
+func (g *Registry) SizeBytes() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	size := int(unsafe.Sizeof(*g)) + cap(g.opts)*int(unsafe.Sizeof(Option(nil)))
+	for name, r := range g.counters {
+		size += int(unsafe.Sizeof(name)) + int(unsafe.Sizeof(r)) + len(name) + mapEntryOverhead
+		size += r.SizeBytes()
+	}
+	for name, until := range g.tombstones {
+		size += int(unsafe.Sizeof(name)) + int(unsafe.Sizeof(until)) + len(name) + mapEntryOverhead
+	}
+
+	return size
+}
@@ -0,0 +1,72 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestRateCounter_SizeBytes(t *testing.T) {
+	small := NewRateCounterWithOptions(1*time.Second, WithResolution(1))
+	large := NewRateCounterWithOptions(1*time.Second, WithResolution(101))
+
+	partialSize := int(unsafe.Sizeof(partial{}))
+	if diff := large.SizeBytes() - small.SizeBytes(); diff != 100*partialSize {
+		t.Error("Expected ", diff, " to equal ", 100*partialSize)
+	}
+
+	tagged := NewRateCounterWithOptions(1*time.Second, WithResolution(1), WithTags(map[string]string{"route": "/users"}))
+	if tagged.SizeBytes() <= small.SizeBytes() {
+		t.Error("Expected tags to add to the size")
+	}
+}
+
+func TestRateCounter_SizeBytes_WithShards(t *testing.T) {
+	plain := NewRateCounter(1*time.Second, WithResolution(4))
+	sharded := NewRateCounter(1*time.Second, WithResolution(4), WithShards(8))
+
+	if diff := sharded.SizeBytes() - plain.SizeBytes(); diff < 4*8*cacheLine {
+		t.Error("Expected ", diff, " to include the shards")
+	}
+}
+
+func TestRateCounter_SizeBytes_Owned(t *testing.T) {
+	plain := NewRateCounter(1*time.Second, WithResolution(4))
+	traced := NewRateCounter(1*time.Second, WithResolution(4), WithTrace(100))
+
+	entrySize := int(unsafe.Sizeof(TraceEntry{}))
+	if diff := traced.SizeBytes() - plain.SizeBytes(); diff < 100*entrySize {
+		t.Error("Expected ", diff, " to include the trace")
+	}
+
+	watched := NewRateCounter(1*time.Second, WithResolution(4))
+	watched.WatchAbove(10, 0, make(chan int64, 1))
+	if watched.SizeBytes() <= plain.SizeBytes() {
+		t.Error("Expected watches to add to the size")
+	}
+
+	ticking := NewRateCounter(1*time.Second, WithResolution(4))
+	ticking.Start()
+	defer ticking.Stop()
+	if ticking.SizeBytes() <= plain.SizeBytes() {
+		t.Error("Expected the ticker to add to the size")
+	}
+}
+
+func TestRegistry_SizeBytes(t *testing.T) {
+	g := NewRegistry(1*time.Second, WithResolution(4))
+	empty := g.SizeBytes()
+
+	r := g.Get("requests")
+	if diff := g.SizeBytes() - empty; diff < r.SizeBytes() {
+		t.Error("Expected ", diff, " to include the counter's ", r.SizeBytes())
+	}
+}
+
+func TestAvgRateCounter_SizeBytes(t *testing.T) {
+	r := NewAvgRateCounter(1 * time.Second)
+
+	if size := r.SizeBytes(); size <= 2*r.hits.SizeBytes() {
+		t.Error("Expected ", size, " to include both counters")
+	}
+}
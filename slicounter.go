@@ -0,0 +1,63 @@
+package ratecounter
+
+import (
+	"strconv"
+	"time"
+)
+
+// An SLICounter is a thread-safe counter which tracks the fraction of
+// observations in the last interval that were under a latency target, for
+// measuring latency SLOs without a full histogram
+type SLICounter struct {
+	good   *RateCounter
+	total  *RateCounter
+	target time.Duration
+}
+
+// NewSLICounter constructs a new SLICounter for the interval provided,
+// counting observations at or under target as good
+func NewSLICounter(intrvl time.Duration, target time.Duration) *SLICounter {
+	return &SLICounter{
+		good:   NewRateCounter(intrvl),
+		total:  NewRateCounter(intrvl),
+		target: target,
+	}
+}
+
+// Observe records an observed latency
+func (s *SLICounter) Observe(latency time.Duration) {
+	// Count the total first so readers never see more good than total
+	s.total.Incr(1)
+	if latency <= s.target {
+		s.good.Incr(1)
+	}
+}
+
+// Good returns the number of observations under the target in the last
+// interval
+func (s *SLICounter) Good() int64 {
+	return s.good.Rate()
+}
+
+// Total returns the number of observations in the last interval
+func (s *SLICounter) Total() int64 {
+	return s.total.Rate()
+}
+
+// Ratio returns the fraction of observations in the last interval which
+// were under the target. With no observations it returns 1, since no
+// request missed the target.
+func (s *SLICounter) Ratio() float64 {
+	good, total := s.good.Rate(), s.total.Rate()
+
+	if total == 0 {
+		return 1
+	}
+
+	return float64(good) / float64(total)
+}
+
+// String returns the ratio formatted to string
+func (s *SLICounter) String() string {
+	return strconv.FormatFloat(s.Ratio(), 'f', 5, 64)
+}
@@ -0,0 +1,38 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSLICounter(t *testing.T) {
+	interval := 200 * time.Millisecond
+	s := NewSLICounter(interval, 100*time.Millisecond)
+
+	if ratio := s.Ratio(); ratio != 1 {
+		t.Error("Expected ", ratio, " to equal ", 1)
+	}
+
+	s.Observe(10 * time.Millisecond)
+	s.Observe(100 * time.Millisecond)
+	s.Observe(50 * time.Millisecond)
+	s.Observe(150 * time.Millisecond)
+
+	if good := s.Good(); good != 3 {
+		t.Error("Expected good ", good, " to equal ", 3)
+	}
+	if total := s.Total(); total != 4 {
+		t.Error("Expected total ", total, " to equal ", 4)
+	}
+	if ratio := s.Ratio(); ratio != 0.75 {
+		t.Error("Expected ", ratio, " to equal ", 0.75)
+	}
+	if str := s.String(); str != "0.75000" {
+		t.Error("Expected ", str, " to equal ", "0.75000")
+	}
+
+	time.Sleep(2 * interval)
+	if ratio := s.Ratio(); ratio != 1 {
+		t.Error("Expected ", ratio, " to equal ", 1)
+	}
+}
@@ -0,0 +1,136 @@
+package ratecounter
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// A Snapshot is a point-in-time copy of a RateCounter's state
+type Snapshot struct {
+	// Rate is the number of events in the last interval
+	Rate int64
+	// Interval is the window the counter covers
+	Interval time.Duration
+	// Buckets holds the value of each partial, oldest first, as recorded
+	// before any sample rate scaling
+	Buckets []int64
+	// Start is when the oldest bucket began
+	Start time.Time
+	// BucketWidth is the length of time each bucket covers
+	BucketWidth time.Duration
+	// Unit, Description and Tags are the counter's metadata. Tags is a copy,
+	// so it may be modified.
+	Unit        string
+	Description string
+	Tags        map[string]string
+}
+
+// BucketStart returns when bucket i began. Bucket i covers BucketStart(i)
+// up to BucketStart(i+1).
+func (s *Snapshot) BucketStart(i int) time.Time {
+	return s.Start.Add(time.Duration(i) * s.BucketWidth)
+}
+
+// Snapshot returns a copy of the counter's current state, without
+// disturbing its rotation
+func (r *RateCounter) Snapshot() Snapshot {
+	var s Snapshot
+	r.SnapshotInto(&s)
+	return s
+}
+
+// SnapshotInto fills s with the counter's current state, reusing the
+// capacity of s.Buckets and the s.Tags map so repeated calls do not
+// allocate
+func (r *RateCounter) SnapshotInto(s *Snapshot) {
+	r.snapshotAt(s, r.now())
+}
+
+// snapshotAt fills s with the counter's state as of now
+func (r *RateCounter) snapshotAt(s *Snapshot, now uint64) {
+	e := r.advance(now)
+
+	s.Rate = r.scale(r.total(e))
+	s.Interval = time.Duration(r.interval) * time.Millisecond
+	s.Buckets = r.bucketsInto(s.Buckets[:0], e)
+	s.BucketWidth = s.Interval / time.Duration(len(s.Buckets))
+	oldest := before(e+1, uint64(len(s.Buckets)))
+	s.Start = time.Unix(0, int64(r.periodStart(oldest))*int64(time.Millisecond))
+	s.Unit = r.unit
+	s.Description = r.description
+	s.Tags = r.tagsInto(s.Tags)
+}
+
+// tagsInto copies the counter's tags into dst, emptied first, making it if
+// needed, and returns the result
+func (r *RateCounter) tagsInto(dst map[string]string) map[string]string {
+	if r.tags == nil {
+		return nil
+	}
+
+	if dst == nil {
+		dst = make(map[string]string, len(r.tags))
+	}
+	for k := range dst {
+		delete(dst, k)
+	}
+	for k, v := range r.tags {
+		dst[k] = v
+	}
+	return dst
+}
+
+// Buckets returns the value of each partial, oldest first
+func (r *RateCounter) Buckets() []int64 {
+	return r.BucketsInto(nil)
+}
+
+// BucketsInto appends the value of each partial, oldest first, to dst[:0]
+// and returns the result. Passing a slice with enough capacity avoids
+// allocating.
+func (r *RateCounter) BucketsInto(dst []int64) []int64 {
+	return r.bucketsInto(dst[:0], r.advance(r.now()))
+}
+
+// bucketsInto appends the value of each partial in the interval ending in
+// period e, oldest first
+func (r *RateCounter) bucketsInto(dst []int64, e uint64) []int64 {
+	resolution := uint64(r.resolution())
+
+	for k := before(e+1, resolution); k < before(e+1, resolution)+resolution; k++ {
+		p := &r.partials[k%uint64(len(r.partials))]
+		if atomic.LoadUint64(&p.stamp) == k {
+			dst = append(dst, p.Value())
+		} else {
+			// Nothing has been recorded in this period
+			dst = append(dst, 0)
+		}
+	}
+
+	return dst
+}
+
+// Merge adds the buckets of s, such as one taken by a process which is
+// shutting down, to the partials covering when each bucket began, so the
+// counter carries on where the other left off. Buckets which have left the
+// window are skipped. A bucket is credited whole to the partial its start
+// falls in, so buckets of a different width are only placed approximately.
+func (r *RateCounter) Merge(s Snapshot) {
+	e := r.advance(r.now())
+
+	for ii, val := range s.Buckets {
+		start := s.BucketStart(ii)
+		if val == 0 || start.Before(time.Unix(0, 0)) {
+			continue
+		}
+
+		period := r.period(uint64(start.Unix())*1000 + uint64(start.Nanosecond()/1000000))
+		if period > e {
+			period = e
+		}
+		if period+uint64(r.resolution()) <= e {
+			continue
+		}
+		r.add(period, val)
+	}
+}
@@ -0,0 +1,173 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateCounter_Snapshot(t *testing.T) {
+	interval := 500 * time.Millisecond
+	r := NewRateCounter(interval).WithResolution(5)
+
+	r.Incr(1)
+	time.Sleep(150 * time.Millisecond)
+	r.Incr(2)
+
+	s := r.Snapshot()
+	if s.Rate != 3 {
+		t.Error("Expected rate ", s.Rate, " to equal ", 3)
+	}
+	if s.Interval != interval {
+		t.Error("Expected interval ", s.Interval, " to equal ", interval)
+	}
+	if len(s.Buckets) != 5 {
+		t.Fatal("Expected ", len(s.Buckets), " buckets to equal ", 5)
+	}
+
+	// The newest partial is last
+	if s.Buckets[4] != 2 {
+		t.Error("Expected newest bucket ", s.Buckets[4], " to equal ", 2)
+	}
+
+	var sum int64
+	for _, b := range s.Buckets {
+		sum += b
+	}
+	if sum != s.Rate {
+		t.Error("Expected bucket sum ", sum, " to equal ", s.Rate)
+	}
+}
+
+func TestRateCounter_SnapshotInto_ReusesBuffer(t *testing.T) {
+	r := NewRateCounter(1 * time.Second)
+	r.Incr(1)
+
+	s := Snapshot{Buckets: make([]int64, 0, 20)}
+	buf := s.Buckets[:1]
+	r.SnapshotInto(&s)
+
+	if &s.Buckets[0] != &buf[0] {
+		t.Error("SnapshotInto did not reuse the provided buffer")
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		r.SnapshotInto(&s)
+	})
+	if allocs != 0 {
+		t.Error("Expected SnapshotInto to not allocate, got", allocs)
+	}
+}
+
+func TestRateCounter_Snapshot_CopiesTags(t *testing.T) {
+	r := NewRateCounter(1*time.Second, WithTags(map[string]string{"route": "/users"}))
+
+	s := r.Snapshot()
+	s.Tags["route"] = "/changed"
+	if tags := r.Tags(); tags["route"] != "/users" {
+		t.Error("Expected ", tags["route"], " to equal ", "/users")
+	}
+
+	// Reusing the snapshot refills its own map without allocating
+	allocs := testing.AllocsPerRun(100, func() {
+		r.SnapshotInto(&s)
+	})
+	if s.Tags["route"] != "/users" {
+		t.Error("Expected ", s.Tags["route"], " to equal ", "/users")
+	}
+	if allocs > 0 {
+		t.Error("Expected SnapshotInto to not allocate, got", allocs)
+	}
+}
+
+func TestRateCounter_BucketsInto(t *testing.T) {
+	r := NewRateCounter(1 * time.Second).WithResolution(4)
+	r.Incr(7)
+
+	dst := make([]int64, 10, 10)
+	dst = r.BucketsInto(dst)
+	if len(dst) != 4 {
+		t.Fatal("Expected ", len(dst), " buckets to equal ", 4)
+	}
+	if dst[3] != 7 {
+		t.Error("Expected newest bucket ", dst[3], " to equal ", 7)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		dst = r.BucketsInto(dst)
+	})
+	if allocs != 0 {
+		t.Error("Expected BucketsInto to not allocate, got", allocs)
+	}
+}
+
+func BenchmarkRateCounter_SnapshotInto(b *testing.B) {
+	r := NewRateCounter(1 * time.Second)
+	r.Incr(1)
+	var s Snapshot
+
+	for i := 0; i < b.N; i++ {
+		r.SnapshotInto(&s)
+	}
+}
+
+func TestRateCounter_Snapshot_BucketTimes(t *testing.T) {
+	clock := newManualClock()
+	start := clock.Now()
+	r := NewRateCounterWithOptions(400*time.Millisecond, WithResolution(4), WithClock(clock))
+
+	r.Incr(1)
+	clock.Add(250 * time.Millisecond)
+	r.Incr(2)
+
+	s := r.Snapshot()
+	if s.BucketWidth != 100*time.Millisecond {
+		t.Error("Expected ", s.BucketWidth, " to equal ", 100*time.Millisecond)
+	}
+
+	// Partials turn every 100ms from creation, so the newest began at 200ms
+	newest := s.BucketStart(len(s.Buckets) - 1)
+	if !newest.Equal(start.Add(200 * time.Millisecond)) {
+		t.Error("Expected newest bucket to start at ", start.Add(200*time.Millisecond), " got ", newest)
+	}
+	if !s.Start.Equal(start.Add(-100 * time.Millisecond)) {
+		t.Error("Expected oldest bucket to start at ", start.Add(-100*time.Millisecond), " got ", s.Start)
+	}
+	if s.Buckets[3] != 2 || s.Buckets[1] != 1 {
+		t.Error("Expected ", s.Buckets, " to equal ", []int64{0, 1, 0, 2})
+	}
+
+	// Taking a snapshot does not move the window
+	if again := r.Snapshot(); !again.Start.Equal(s.Start) {
+		t.Error("Expected ", again.Start, " to equal ", s.Start)
+	}
+}
+
+func TestRateCounter_Merge(t *testing.T) {
+	clock := newManualClock()
+	old := NewRateCounterWithOptions(400*time.Millisecond, WithResolution(4), WithClock(clock))
+	old.Incr(1)
+	clock.Add(200 * time.Millisecond)
+	old.Incr(2)
+	clock.Add(50 * time.Millisecond)
+
+	// A counter started later, with its partials offset, takes over
+	r := NewRateCounterWithOptions(400*time.Millisecond, WithResolution(4), WithClock(clock))
+	r.Merge(old.Snapshot())
+	if val := r.Rate(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+
+	// The merged events leave the window when they would have
+	clock.Add(100 * time.Millisecond)
+	if val := r.Rate(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+
+	// Buckets which have already left the window are skipped
+	s := old.Snapshot()
+	clock.Add(time.Second)
+	r.Merge(s)
+	if val := r.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
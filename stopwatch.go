@@ -0,0 +1,30 @@
+package ratecounter
+
+import "time"
+
+// A Stopwatch times one operation and records it into an AvgRateCounter
+//
+//	defer counter.Start().Done()
+type Stopwatch struct {
+	counter *AvgRateCounter
+	start   time.Time
+}
+
+// Start returns a Stopwatch which records into the counter when Done is
+// called
+func (a *AvgRateCounter) Start() Stopwatch {
+	return Stopwatch{counter: a, start: time.Now()}
+}
+
+// Done records the time since Start, in nanoseconds, and returns it
+func (s Stopwatch) Done() time.Duration {
+	elapsed := time.Since(s.start)
+	s.counter.Incr(elapsed.Nanoseconds())
+	return elapsed
+}
+
+// Time calls f and records how long it took, in nanoseconds
+func (a *AvgRateCounter) Time(f func()) {
+	defer a.Start().Done()
+	f()
+}
@@ -0,0 +1,43 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAvgRateCounter_Time(t *testing.T) {
+	r := NewAvgRateCounter(1 * time.Second)
+
+	r.Time(func() {
+		time.Sleep(20 * time.Millisecond)
+	})
+
+	if hits := r.Hits(); hits != 1 {
+		t.Error("Expected hits ", hits, " to equal ", 1)
+	}
+	if avg := time.Duration(r.Rate()); avg < 20*time.Millisecond {
+		t.Error("Expected average ", avg, " to be at least ", 20*time.Millisecond)
+	}
+}
+
+func TestAvgRateCounter_StartDone(t *testing.T) {
+	r := NewAvgRateCounter(1 * time.Second)
+
+	func() {
+		defer r.Start().Done()
+		time.Sleep(10 * time.Millisecond)
+	}()
+
+	sw := r.Start()
+	time.Sleep(30 * time.Millisecond)
+	if elapsed := sw.Done(); elapsed < 30*time.Millisecond {
+		t.Error("Expected elapsed ", elapsed, " to be at least ", 30*time.Millisecond)
+	}
+
+	if hits := r.Hits(); hits != 2 {
+		t.Error("Expected hits ", hits, " to equal ", 2)
+	}
+	if avg := time.Duration(r.Rate()); avg < 20*time.Millisecond {
+		t.Error("Expected average ", avg, " to be at least ", 20*time.Millisecond)
+	}
+}
@@ -0,0 +1,78 @@
+package ratecounter
+
+import "time"
+
+// ticker rotates a RateCounter's partials in the background
+type ticker struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Start rotates the partials from a background goroutine, once per partial,
+// so the maximum and smoothed rates are updated on time while no events
+// arrive, rather than caught up on the next call. Reads then never do the
+// bookkeeping for an idle spell, though they still sum the partials. Call
+// Stop to end the goroutine.
+// Calling Start on a started counter, or on one whose WithContext context
+// is done, does nothing.
+func (r *RateCounter) Start() {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.ticker != nil || (r.ctx != nil && r.ctx.Err() != nil) {
+		return
+	}
+
+	t := &ticker{stop: make(chan struct{}), done: make(chan struct{})}
+	r.ticker = t
+
+	period := time.Duration(r.interval) * time.Millisecond / time.Duration(r.resolution())
+	if period < time.Millisecond {
+		period = time.Millisecond
+	}
+
+	go func() {
+		defer close(t.done)
+
+		tick := time.NewTicker(period)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-tick.C:
+				r.advance(r.now())
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the goroutine started by Start and waits for it to exit.
+// Calling Stop on a counter which was not started does nothing.
+func (r *RateCounter) Stop() {
+	r.Lock()
+	t := r.ticker
+	r.ticker = nil
+	r.Unlock()
+
+	if t == nil {
+		return
+	}
+
+	close(t.stop)
+	<-t.done
+}
+
+// Close stops the counter's ticker and removes its watches, so nothing it
+// started is left running. The counter keeps working, rotated as it is
+// used. Close is idempotent and always returns nil; it is there so a
+// counter can be shut down alongside other io.Closers.
+func (r *RateCounter) Close() error {
+	r.Lock()
+	r.watches = nil
+	r.Unlock()
+
+	r.Stop()
+	return nil
+}
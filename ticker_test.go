@@ -0,0 +1,128 @@
+package ratecounter
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateCounter_Start(t *testing.T) {
+	r := NewRateCounter(100 * time.Millisecond).WithResolution(4)
+	r.Start()
+	defer r.Stop()
+
+	// Starting twice keeps the one goroutine
+	r.Start()
+
+	r.Incr(5)
+	before := atomic.LoadUint64(&r.epoch)
+	time.Sleep(250 * time.Millisecond)
+
+	// The partials were rotated without any reads
+	if after := atomic.LoadUint64(&r.epoch); after < before+4 {
+		t.Error("Expected the counter to move on from ", before, " without reads, got ", after)
+	}
+}
+
+func TestRateCounter_Stop(t *testing.T) {
+	r := NewRateCounter(100 * time.Millisecond)
+
+	// Stopping a counter which never started is harmless
+	r.Stop()
+
+	r.Start()
+	r.Stop()
+	r.Stop()
+
+	if r.ticker != nil {
+		t.Error("Expected the ticker to be cleared")
+	}
+
+	// The counter can be restarted
+	r.Start()
+	r.Stop()
+}
+
+func TestWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := NewRegistry(100*time.Millisecond, WithResolution(4), WithTicker(), WithContext(ctx))
+	r := g.Get("jobs")
+	r.WatchAbove(10, 0, make(chan int64, 1))
+	r.Lock()
+	tick := r.ticker
+	r.Unlock()
+
+	cancel()
+	select {
+	case <-tick.done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the ticker to end with the context")
+	}
+
+	r.Lock()
+	watches := len(r.watches)
+	r.Unlock()
+	if watches != 0 {
+		t.Error("Expected ", watches, " watches to equal ", 0)
+	}
+
+	// Stopping afterwards is still harmless, and the counter still works
+	g.Stop()
+	r.Incr(2)
+	if val := r.Rate(); val != 2 {
+		t.Error("Expected ", val, " to equal ", 2)
+	}
+}
+
+func TestWithContext_NoTicker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewRateCounter(100*time.Millisecond, WithContext(ctx))
+	r.WatchAbove(10, 0, make(chan int64, 1))
+
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for {
+		r.Lock()
+		watches := len(r.watches)
+		r.Unlock()
+		if watches == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the watches to be removed with the context")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Once the context is done the counter cannot be started again
+	r.Start()
+	r.Lock()
+	started := r.ticker != nil
+	r.Unlock()
+	if started {
+		t.Error("Expected Start to do nothing once the context is done")
+	}
+}
+
+func TestRateCounter_Close(t *testing.T) {
+	r := NewRateCounter(100*time.Millisecond, WithTicker())
+	w := r.WatchAbove(1, 0, make(chan int64, 1))
+
+	for i := 0; i < 2; i++ {
+		if err := r.Close(); err != nil {
+			t.Error("Expected ", err, " to be nil")
+		}
+	}
+	if r.ticker != nil || len(r.watches) != 0 {
+		t.Error("Expected the ticker and watches to be gone")
+	}
+	if err := w.Close(); err != nil {
+		t.Error("Expected ", err, " to be nil")
+	}
+
+	r.Incr(3)
+	if val := r.Rate(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+}
@@ -0,0 +1,78 @@
+package ratecounter
+
+import (
+	"sync"
+	"time"
+)
+
+// A TraceEntry is one increment recorded by a counter's trace
+type TraceEntry struct {
+	At    time.Time
+	Val   int64
+	Label string
+}
+
+// trace is a bounded ring of the most recent increments
+type trace struct {
+	entries []TraceEntry
+	next    int
+	full    bool
+	sync.Mutex
+}
+
+func (t *trace) add(millis uint64, val int64, label string) {
+	t.Lock()
+	t.entries[t.next] = TraceEntry{
+		At:    time.Unix(0, int64(millis)*1000000),
+		Val:   val,
+		Label: label,
+	}
+	t.next++
+	if t.next == len(t.entries) {
+		t.next, t.full = 0, true
+	}
+	t.Unlock()
+}
+
+func (t *trace) dump() []TraceEntry {
+	t.Lock()
+	defer t.Unlock()
+
+	if !t.full {
+		return append([]TraceEntry(nil), t.entries[:t.next]...)
+	}
+
+	entries := make([]TraceEntry, 0, len(t.entries))
+	entries = append(entries, t.entries[t.next:]...)
+	return append(entries, t.entries[:t.next]...)
+}
+
+// WithTrace keeps a trace of the last size increments, with their times and
+// labels, which can be dumped with Trace when investigating a spike
+func WithTrace(size int) Option {
+	if size < 1 {
+		panic("RateCounter trace size cannot be less than 1")
+	}
+
+	return func(r *RateCounter) {
+		r.trace = &trace{entries: make([]TraceEntry, size)}
+	}
+}
+
+// IncrWith Add an event into the RateCounter, recording label with it in
+// the trace if one is kept
+func (r *RateCounter) IncrWith(val int64, label string) {
+	r.incr(val)
+	if r.trace != nil {
+		r.trace.add(r.now(), val, label)
+	}
+}
+
+// Trace returns the most recent increments, oldest first. It is empty unless
+// the counter was built with WithTrace.
+func (r *RateCounter) Trace() []TraceEntry {
+	if r.trace == nil {
+		return nil
+	}
+	return r.trace.dump()
+}
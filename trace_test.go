@@ -0,0 +1,59 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithTrace(t *testing.T) {
+	start := time.Date(2018, time.October, 8, 12, 0, 0, 0, time.UTC)
+	p := NewReplay(1*time.Second, start, WithTrace(3))
+	r := p.Counter()
+
+	if entries := r.Trace(); len(entries) != 0 {
+		t.Error("Expected ", entries, " to be empty")
+	}
+
+	r.Incr(1)
+	p.advance(start.Add(10 * time.Millisecond))
+	r.IncrWith(2, "a")
+	entries := r.Trace()
+	if len(entries) != 2 {
+		t.Fatal("Expected ", len(entries), " entries to equal ", 2)
+	}
+	if !entries[0].At.Equal(start) || entries[0].Val != 1 || entries[0].Label != "" {
+		t.Error("Unexpected first entry", entries[0])
+	}
+	if !entries[1].At.Equal(start.Add(10*time.Millisecond)) || entries[1].Label != "a" {
+		t.Error("Unexpected second entry", entries[1])
+	}
+
+	// Only the most recent entries are kept, oldest first
+	r.IncrWith(3, "b")
+	r.IncrWith(4, "c")
+	entries = r.Trace()
+	if len(entries) != 3 {
+		t.Fatal("Expected ", len(entries), " entries to equal ", 3)
+	}
+	for i, label := range []string{"a", "b", "c"} {
+		if entries[i].Label != label {
+			t.Error("Expected ", entries[i].Label, " to equal ", label)
+		}
+	}
+
+	if val := r.Rate(); val != 10 {
+		t.Error("Expected ", val, " to equal ", 10)
+	}
+}
+
+func TestWithTrace_Disabled(t *testing.T) {
+	r := NewRateCounter(1 * time.Second)
+
+	r.IncrWith(1, "a")
+	if entries := r.Trace(); entries != nil {
+		t.Error("Expected ", entries, " to be nil")
+	}
+	if val := r.Rate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+}
@@ -0,0 +1,45 @@
+package ratecounter
+
+import (
+	"math/rand"
+	"time"
+)
+
+// A TraceSampler decides which requests to trace so that trace volume stays
+// close to a target number per second however traffic scales. Each request
+// is sampled with probability target / current request rate.
+type TraceSampler struct {
+	rate      *RateCounter
+	perSecond float64
+}
+
+// NewTraceSampler constructs a new TraceSampler aiming for perSecond traces
+// a second, measuring the request rate over window. A longer window gives a
+// steadier probability but reacts to traffic changes more slowly.
+func NewTraceSampler(perSecond float64, window time.Duration) *TraceSampler {
+	if perSecond < 0 {
+		panic("TraceSampler target cannot be negative")
+	}
+
+	return &TraceSampler{
+		rate:      NewRateCounter(window),
+		perSecond: perSecond,
+	}
+}
+
+// Sample records a request and reports whether it should be traced
+func (s *TraceSampler) Sample() bool {
+	s.rate.Incr(1)
+	return rand.Float64() < s.Probability()
+}
+
+// Probability returns the chance each request currently has of being
+// sampled, for tracing libraries which take a sampling ratio. It is 1 until
+// the request rate exceeds the target.
+func (s *TraceSampler) Probability() float64 {
+	requests := s.rate.RatePerSecond()
+	if requests <= s.perSecond {
+		return 1
+	}
+	return s.perSecond / requests
+}
@@ -0,0 +1,40 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTraceSampler(t *testing.T) {
+	s := NewTraceSampler(10, 1*time.Second)
+
+	// Below the target everything is traced
+	for i := 0; i < 10; i++ {
+		if !s.Sample() {
+			t.Fatal("Expected request ", i, " to be sampled")
+		}
+	}
+
+	for i := 0; i < 990; i++ {
+		s.Sample()
+	}
+	if p := s.Probability(); p != 0.01 {
+		t.Error("Expected ", p, " to equal ", 0.01)
+	}
+}
+
+func TestTraceSampler_Volume(t *testing.T) {
+	s := NewTraceSampler(100, 1*time.Second)
+
+	sampled := 0
+	for i := 0; i < 100000; i++ {
+		if s.Sample() {
+			sampled++
+		}
+	}
+
+	// About 100 plus the harmonic tail while the rate climbs
+	if sampled < 100 || sampled > 2000 {
+		t.Error("Expected ", sampled, " to be near ", 100)
+	}
+}
@@ -0,0 +1,74 @@
+package ratecounter
+
+import (
+	"math"
+	"strconv"
+)
+
+// A View is a read-only rate, either a RateCounter or one derived from other
+// views with Sum, Sub and Scale. Derived views compute lazily on Rate,
+// bringing every underlying counter up to the same instant first, so a
+// composite such as "total minus healthchecks" needs no extra Incr calls.
+type View interface {
+	Rate() int64
+	// rateAt returns the rate with every counter rotated to now
+	rateAt(now uint64) int64
+	// now returns the current time on the counters' clock, in milliseconds
+	now() uint64
+}
+
+func (r *RateCounter) rateAt(now uint64) int64 {
+	return r.scale(r.total(r.advance(now)))
+}
+
+// A view derives a rate from other views, reading the time from the first
+// of them
+type view struct {
+	rate  func(now uint64) int64
+	views []View
+}
+
+func (v view) rateAt(now uint64) int64 {
+	return v.rate(now)
+}
+
+func (v view) now() uint64 {
+	if len(v.views) == 0 {
+		return UnixMilli()
+	}
+	return v.views[0].now()
+}
+
+func (v view) Rate() int64 {
+	return v.rate(v.now())
+}
+
+func (v view) String() string {
+	return strconv.FormatInt(v.Rate(), 10)
+}
+
+// Sum returns a View of the total rate of all the views
+func Sum(views ...View) View {
+	return view{func(now uint64) int64 {
+		var sum int64
+		for _, v := range views {
+			sum += v.rateAt(now)
+		}
+		return sum
+	}, views}
+}
+
+// Sub returns a View of the rate of a minus the rate of b
+func Sub(a, b View) View {
+	return view{func(now uint64) int64 {
+		return a.rateAt(now) - b.rateAt(now)
+	}, []View{a, b}}
+}
+
+// Scale returns a View of the rate of v multiplied by k, rounded to the
+// nearest integer
+func Scale(v View, k float64) View {
+	return view{func(now uint64) int64 {
+		return int64(math.Round(float64(v.rateAt(now)) * k))
+	}, []View{v}}
+}
@@ -0,0 +1,72 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestViews(t *testing.T) {
+	interval := 200 * time.Millisecond
+	total := NewRateCounter(interval)
+	healthchecks := NewRateCounter(interval)
+	other := NewRateCounter(interval)
+
+	total.Incr(10)
+	healthchecks.Incr(3)
+	other.Incr(5)
+
+	if val := Sub(total, healthchecks).Rate(); val != 7 {
+		t.Error("Expected ", val, " to equal ", 7)
+	}
+	if val := Sum(total, other).Rate(); val != 15 {
+		t.Error("Expected ", val, " to equal ", 15)
+	}
+	if val := Sum().Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+	if val := Scale(total, 0.25).Rate(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+
+	// Views compose and stay live
+	composite := Scale(Sub(Sum(total, other), healthchecks), 2)
+	if val := composite.Rate(); val != 24 {
+		t.Error("Expected ", val, " to equal ", 24)
+	}
+	other.Incr(1)
+	if val := composite.Rate(); val != 26 {
+		t.Error("Expected ", val, " to equal ", 26)
+	}
+
+	time.Sleep(2 * interval)
+	if val := composite.Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestViews_WithClock(t *testing.T) {
+	clock := newManualClock()
+	a := NewRateCounter(1*time.Second, WithClock(clock))
+	b := NewRateCounter(1*time.Second, WithClock(clock))
+
+	// The manual clock is years behind the wall clock, so reading the wall
+	// clock would expire everything
+	a.Incr(5)
+	b.Incr(2)
+
+	if val := Sum(a, b).Rate(); val != 7 {
+		t.Error("Expected ", val, " to equal ", 7)
+	}
+	if val := Sub(a, b).Rate(); val != 3 {
+		t.Error("Expected ", val, " to equal ", 3)
+	}
+	if val := Scale(a, 2).Rate(); val != 10 {
+		t.Error("Expected ", val, " to equal ", 10)
+	}
+
+	// The counters still expire on their own clock
+	clock.Add(2 * time.Second)
+	if val := Sum(a, Scale(b, 2)).Rate(); val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
@@ -0,0 +1,117 @@
+package ratecounter
+
+// A Watch notifies a channel when a RateCounter's rate crosses a
+// threshold. The rate is checked each time a partial rotates, so with
+// WithTicker a crossing is seen within one partial of happening.
+type Watch struct {
+	counter    *RateCounter
+	threshold  int64
+	hysteresis int64
+	// Whether the watch fires on rising above the threshold, rather than
+	// falling below it
+	above bool
+	ch    chan<- int64
+	// Whether the rate is past the threshold, so the watch waits for it to
+	// come back by the hysteresis before firing again
+	crossed bool
+}
+
+// WatchAbove sends the rate to ch each time it rises above threshold. After
+// firing the watch is quiet until the rate falls to threshold-hysteresis or
+// below, so a rate hovering around the threshold does not flap. Sends do
+// not block; if ch is not ready the notification is dropped, so ch should
+// be buffered. A rate already above the threshold when the watch is made
+// does not fire until it has come back.
+func (r *RateCounter) WatchAbove(threshold, hysteresis int64, ch chan<- int64) *Watch {
+	return r.watch(&Watch{threshold: threshold, hysteresis: hysteresis, above: true, ch: ch})
+}
+
+// WatchBelow sends the rate to ch each time it falls below threshold, then
+// is quiet until the rate rises to threshold+hysteresis or above, as
+// WatchAbove does the other way up
+func (r *RateCounter) WatchBelow(threshold, hysteresis int64, ch chan<- int64) *Watch {
+	return r.watch(&Watch{threshold: threshold, hysteresis: hysteresis, ch: ch})
+}
+
+func (r *RateCounter) watch(w *Watch) *Watch {
+	if w.hysteresis < 0 {
+		panic("Watch hysteresis cannot be negative")
+	}
+
+	w.counter = r
+	w.crossed = w.past(r.Rate())
+
+	r.Lock()
+	r.watches = append(r.watches, w)
+	r.Unlock()
+
+	return w
+}
+
+// Stop removes the watch from its counter. No more notifications are sent
+// once it returns.
+func (w *Watch) Stop() {
+	r := w.counter
+	r.Lock()
+	defer r.Unlock()
+
+	for ii, other := range r.watches {
+		if other == w {
+			r.watches = append(r.watches[:ii], r.watches[ii+1:]...)
+			return
+		}
+	}
+}
+
+// Close is Stop, for shutting a watch down alongside other io.Closers. It
+// is idempotent and always returns nil.
+func (w *Watch) Close() error {
+	w.Stop()
+	return nil
+}
+
+// past reports whether rate is beyond the threshold
+func (w *Watch) past(rate int64) bool {
+	if w.above {
+		return rate > w.threshold
+	}
+	return rate < w.threshold
+}
+
+// back reports whether rate has come back from the threshold by the
+// hysteresis
+func (w *Watch) back(rate int64) bool {
+	if w.above {
+		return rate <= w.threshold-w.hysteresis
+	}
+	return rate >= w.threshold+w.hysteresis
+}
+
+// check notifies the channel if rate has crossed the threshold. The caller
+// must hold the counter's lock.
+func (w *Watch) check(rate int64) {
+	switch {
+	case !w.crossed && w.past(rate):
+		w.crossed = true
+		select {
+		case w.ch <- rate:
+		default:
+		}
+	case w.crossed && w.back(rate):
+		w.crossed = false
+	}
+}
+
+// notify checks the counter's watches against the rate in period e
+func (r *RateCounter) notify(e uint64) {
+	r.Lock()
+	defer r.Unlock()
+
+	if len(r.watches) == 0 {
+		return
+	}
+	rate := r.scale(r.total(e))
+	for _, w := range r.watches {
+		w.check(rate)
+	}
+}
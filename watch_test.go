@@ -0,0 +1,104 @@
+package ratecounter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateCounter_WatchAbove(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounter(400*time.Millisecond, WithResolution(4), WithClock(clock))
+	ch := make(chan int64, 10)
+	w := r.WatchAbove(5, 2, ch)
+
+	expect := func(expected ...int64) {
+		for _, e := range expected {
+			select {
+			case val := <-ch:
+				if val != e {
+					t.Error("Expected ", val, " to equal ", e)
+				}
+			default:
+				t.Error("Expected a notification of ", e)
+			}
+		}
+		select {
+		case val := <-ch:
+			t.Error("Expected no more notifications, got ", val)
+		default:
+		}
+	}
+
+	// Crossings are seen when the partials rotate
+	r.Incr(6)
+	expect()
+	clock.Add(100 * time.Millisecond)
+	r.Incr(1)
+	expect(6)
+
+	// Still above, so quiet
+	clock.Add(100 * time.Millisecond)
+	r.Incr(0)
+	expect()
+
+	// Falling to 4 is not back far enough
+	r.Incr(-3)
+	clock.Add(100 * time.Millisecond)
+	r.Incr(3)
+	expect()
+
+	// The 6 leaves the window, which puts the rate at 1 and re-arms it
+	clock.Add(100 * time.Millisecond)
+	r.Incr(6)
+	expect()
+	clock.Add(100 * time.Millisecond)
+	r.Incr(0)
+	expect(6)
+
+	// Once stopped nothing more is sent
+	w.Stop()
+	clock.Add(1 * time.Second)
+	r.Incr(10)
+	clock.Add(100 * time.Millisecond)
+	r.Incr(0)
+	expect()
+}
+
+func TestRateCounter_WatchBelow(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounter(400*time.Millisecond, WithResolution(4), WithClock(clock))
+	ch := make(chan int64, 10)
+
+	// Already below, so it waits for the rate to rise first
+	r.WatchBelow(5, 1, ch)
+	clock.Add(100 * time.Millisecond)
+	r.Incr(6)
+	clock.Add(100 * time.Millisecond)
+	r.Rate()
+	if len(ch) != 0 {
+		t.Error("Expected no notifications, got ", len(ch))
+	}
+
+	// The 6 leaves the window
+	clock.Add(400 * time.Millisecond)
+	r.Rate()
+	if len(ch) != 1 {
+		t.Fatal("Expected a notification, got ", len(ch))
+	}
+	if val := <-ch; val != 0 {
+		t.Error("Expected ", val, " to equal ", 0)
+	}
+}
+
+func TestRateCounter_WatchAbove_FullChannel(t *testing.T) {
+	clock := newManualClock()
+	r := NewRateCounter(400*time.Millisecond, WithResolution(4), WithClock(clock))
+	r.WatchAbove(0, 0, make(chan int64))
+
+	// Nobody is receiving, which must not block the counter
+	r.Incr(1)
+	clock.Add(100 * time.Millisecond)
+	if val := r.Rate(); val != 1 {
+		t.Error("Expected ", val, " to equal ", 1)
+	}
+}